@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/shashwatrathod/url-shortner/internal/auth"
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+const usage = `usage: go-short [command]
+
+Without a command the HTTP server is started.
+
+commands:
+  apikey create [--tenant <id>] --name <name> --scopes <scope,...>
+                                                     mint an API key, only valid on the tenant's domain
+  apikey revoke <id>                                 revoke an API key
+  tenant create --id <id> --name <name> --domain <domain>
+                                                     add a tenant serving its own short domain
+  alias get [--tenant <id>] <alias>                  show an alias and the shard storing it
+  alias create [--tenant <id>] [--owner <subject>] [--redirect-code <code>] [--expires-at <time>] <url>
+                                                     create an alias like POST /api/create
+  alias delete [--tenant <id>] <alias>               delete an alias and evict it from the cache
+  alias export [--format ndjson|csv] [--tenant <id>] [--created-from <time>] [--created-to <time>] [--out <file>]
+                                                     export aliases of all shards, to stdout by default
+  alias import [--format ndjson|csv] [--tenant <id>] [--conflict skip|overwrite|fail] <file|->
+                                                     import exported aliases, idempotently
+  alias renormalize                                  recompute normalized URLs, e.g. after upgrading or changing URL_NORMALIZE_*
+  migrate up|status [--shard <id|name>]              apply or list migrations, on every shard by default
+  migrate down [--to <version>] --shard <id|name>|--all
+                                                     roll back the last migration, or down to a version
+  cache inspect [--tenant <id>] <alias>              show the cached redirect record of an alias
+  cache flush [--store aliases|rate_limits]          delete every key of a cache store
+  shard locate [--tenant <id>] <alias>               show which shard owns an alias
+  shard stamp [--yes]                                compare the shards with the configuration, and with --yes
+                                                     record the configured shard IDs after adding or removing shards
+  config validate [--connect]                        check the configuration, and optionally the connections`
+
+// runs a CLI subcommand against the configured shards instead of starting the server.
+func runCommand(conf *config.Config, args []string) error {
+	switch args[0] {
+	case "apikey":
+		return runApiKeyCommand(conf, args[1:])
+	case "tenant":
+		return runTenantCommand(conf, args[1:])
+	case "alias":
+		return runAliasCommand(conf, args[1:])
+	case "migrate":
+		return runMigrateCommand(conf, args[1:])
+	case "cache":
+		return runCacheCommand(conf, args[1:])
+	case "shard":
+		return runShardCommand(conf, args[1:])
+	default:
+		return fmt.Errorf("unknown command '%s'\n%s", args[0], usage)
+	}
+}
+
+func runApiKeyCommand(conf *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", usage)
+	}
+
+	dbManager, err := initDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	apiKeyDao := dao.NewApiKeyDao(dbManager)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		tenantID := flags.String("tenant", dao.DefaultTenantID, "tenant the key belongs to")
+		name := flags.String("name", "", "human readable name of the key")
+		scopes := flags.String("scopes", "", "comma-separated scopes: "+strings.Join(auth.Scopes, ", "))
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if *name == "" {
+			return fmt.Errorf("--name is required")
+		}
+
+		apiKey, key, err := auth.MintAPIKey(ctx, apiKeyDao, *name, splitList(*scopes), *tenantID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("id:     %s\ntenant: %s\nscopes: %s\nkey:    %s\n", apiKey.ID, apiKey.TenantID, strings.Join(apiKey.Scopes, ","), key)
+		fmt.Println("Store the key now, it can't be shown again.")
+		return nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: apikey revoke <id>")
+		}
+
+		revoked, err := apiKeyDao.RevokeApiKey(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return fmt.Errorf("no active API key with id '%s'", args[1])
+		}
+
+		fmt.Printf("revoked API key %s\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown subcommand '%s'\n%s", args[0], usage)
+	}
+}
+
+func runTenantCommand(conf *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return fmt.Errorf("missing or unknown subcommand\n%s", usage)
+	}
+
+	flags := flag.NewFlagSet("tenant create", flag.ContinueOnError)
+	id := flags.String("id", "", "identifier of the tenant, matched against the tenant claim of bearer tokens")
+	name := flags.String("name", "", "human readable name of the tenant")
+	domain := flags.String("domain", "", "short domain of the tenant, e.g. sho.rt")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *id == "" || *name == "" || *domain == "" {
+		return fmt.Errorf("--id, --name and --domain are required")
+	}
+	if len(*id) > 32 || strings.ContainsAny(*id, "/|:") {
+		return fmt.Errorf("--id must be at most 32 characters and can't contain '/', '|' or ':'")
+	}
+	if *id == dao.DefaultTenantID {
+		return fmt.Errorf("'%s' is reserved for the default tenant", dao.DefaultTenantID)
+	}
+
+	dbManager, err := initDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	tenant, err := dao.NewTenantDao(dbManager).CreateTenant(context.Background(), &dao.Tenant{
+		ID:     *id,
+		Name:   *name,
+		Domain: *domain,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created tenant %s serving %s\n", tenant.ID, tenant.Domain)
+	return nil
+}
+
+// splits a comma-separated list, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
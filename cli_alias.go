@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/cache"
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+	"github.com/shashwatrathod/url-shortner/internal/transfer"
+)
+
+func runAliasCommand(conf *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", usage)
+	}
+
+	switch args[0] {
+	case "get":
+		return runAliasGetCommand(conf, args[1:])
+	case "create":
+		return runAliasCreateCommand(conf, args[1:])
+	case "delete":
+		return runAliasDeleteCommand(conf, args[1:])
+	case "export":
+		return runAliasExportCommand(conf, args[1:])
+	case "import":
+		return runAliasImportCommand(conf, args[1:])
+	case "renormalize":
+		return runAliasRenormalizeCommand(conf, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand '%s'\n%s", args[0], usage)
+	}
+}
+
+func runAliasGetCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("alias get", flag.ContinueOnError)
+	tenantID := flags.String("tenant", dao.DefaultTenantID, "tenant of the alias")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: alias get [--tenant <id>] <alias>")
+	}
+
+	// looking at aliases neither migrates the shards nor stamps their metadata.
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	urlAlias, err := dao.NewUrlAliasDao(dbManager).FindByAlias(context.Background(), *tenantID, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if urlAlias == nil {
+		return fmt.Errorf("no alias '%s' in tenant '%s'", flags.Arg(0), *tenantID)
+	}
+
+	shardName, err := dbManager.ShardNameByShardKey(dao.AliasKey(urlAlias.TenantID, urlAlias.Alias))
+	if err != nil {
+		return err
+	}
+
+	printUrlAlias(urlAlias)
+	fmt.Printf("shard:      %s\n", shardName)
+	return nil
+}
+
+// creates an alias the way POST /api/create does: the URL is checked against the URL policy,
+// and an existing alias of the same URL, owner and expiry is reused.
+func runAliasCreateCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("alias create", flag.ContinueOnError)
+	tenantID := flags.String("tenant", dao.DefaultTenantID, "tenant to create the alias in")
+	ownerID := flags.String("owner", "", "subject owning the alias, e.g. the id of an API key")
+	redirectCode := flags.Int("redirect-code", conf.AliasConfig.DefaultRedirectCode, "HTTP status used for the redirect: 301, 302, 307 or 308")
+	expiresAt := flags.String("expires-at", "", "instant after which the alias stops redirecting (RFC3339)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: alias create [flags] <url>")
+	}
+	originalUrl := flags.Arg(0)
+
+	if !config.IsSupportedRedirectCode(*redirectCode) {
+		return fmt.Errorf("--redirect-code must be one of 301, 302, 307 or 308")
+	}
+	expiry, err := parseTimeFlag("expires-at", *expiresAt)
+	if err != nil {
+		return err
+	}
+	if expiry != nil && !expiry.After(time.Now()) {
+		return fmt.Errorf("--expires-at must be in the future")
+	}
+
+	dbManager, err := initDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	ctx := context.Background()
+	appEnv := middleware.NewAppEnv(conf, dbManager, nil, nil, nil)
+	if err := appEnv.TenantDomains.Refresh(ctx); err != nil {
+		return err
+	}
+	if appEnv.UrlPolicy, err = initUrlPolicy(conf, appEnv.TenantDomains); err != nil {
+		return err
+	}
+
+	violation, err := appEnv.UrlPolicy.Check(ctx, originalUrl, "")
+	if err != nil {
+		return err
+	}
+	if violation != nil {
+		return fmt.Errorf("the URL isn't allowed (%s): %s", violation.Rule, violation.Message)
+	}
+
+	normalizedUrl, err := appEnv.UrlNormalizer.Normalize(originalUrl)
+	if err != nil {
+		return err
+	}
+
+	existingAlias, err := appEnv.UrlAliasDao.FindByOriginalUrl(ctx, dao.OriginalUrlLookup{
+		TenantID:      *tenantID,
+		NormalizedUrl: normalizedUrl,
+		RedirectCode:  *redirectCode,
+		OwnerID:       *ownerID,
+		ExpiresAt:     expiry,
+	})
+	if err != nil {
+		return err
+	}
+	if existingAlias != nil {
+		fmt.Println("reusing an existing alias")
+		printUrlAlias(existingAlias)
+		return nil
+	}
+
+	alias, err := core.GenerateAlias(originalUrl, appEnv.AliasingStrategy, appEnv.ReservedAliases)
+	if err != nil {
+		return err
+	}
+
+	urlAlias, err := appEnv.UrlAliasDao.CreateUrlAlias(ctx, &dao.UrlAlias{
+		TenantID:      *tenantID,
+		Alias:         alias,
+		OriginalURL:   originalUrl,
+		NormalizedURL: normalizedUrl,
+		RedirectCode:  *redirectCode,
+		OwnerID:       *ownerID,
+		ExpiresAt:     expiry,
+	})
+	if err != nil {
+		return err
+	}
+
+	printUrlAlias(urlAlias)
+	return nil
+}
+
+func runAliasDeleteCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("alias delete", flag.ContinueOnError)
+	tenantID := flags.String("tenant", dao.DefaultTenantID, "tenant of the alias")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: alias delete [--tenant <id>] <alias>")
+	}
+	alias := flags.Arg(0)
+
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	ctx := context.Background()
+	deleted, err := dao.NewUrlAliasDao(dbManager).DeleteUrlAlias(ctx, *tenantID, alias)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("no alias '%s' in tenant '%s'", alias, *tenantID)
+	}
+
+	// a cached record would keep the alias redirecting.
+	evictAliases(ctx, conf, []string{dao.AliasKey(*tenantID, alias)})
+
+	fmt.Printf("deleted alias %s of tenant %s\n", alias, *tenantID)
+	return nil
+}
+
+func printUrlAlias(urlAlias *dao.UrlAlias) {
+	fmt.Printf("alias:      %s\n", urlAlias.Alias)
+	fmt.Printf("tenant:     %s\n", urlAlias.TenantID)
+	fmt.Printf("url:        %s\n", urlAlias.OriginalURL)
+	fmt.Printf("redirect:   %d\n", urlAlias.RedirectCode)
+	if urlAlias.OwnerID != "" {
+		fmt.Printf("owner:      %s\n", urlAlias.OwnerID)
+	}
+	if urlAlias.ExpiresAt != nil {
+		fmt.Printf("expires at: %s\n", urlAlias.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Printf("created at: %s\n", urlAlias.CreatedAt.UTC().Format(time.RFC3339))
+}
+
+func runAliasExportCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("alias export", flag.ContinueOnError)
+	formatName := flags.String("format", "", "ndjson or csv, derived from --out if omitted")
+	tenantID := flags.String("tenant", "", "only export aliases of this tenant")
+	createdFrom := flags.String("created-from", "", "only export aliases created at or after this instant (RFC3339)")
+	createdTo := flags.String("created-to", "", "only export aliases created before this instant (RFC3339)")
+	out := flags.String("out", "", "file to write, stdout if omitted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := transferFormat(*formatName, *out)
+	if err != nil {
+		return err
+	}
+
+	filter := dao.UrlAliasExportFilter{TenantID: *tenantID}
+	if filter.CreatedFrom, err = parseTimeFlag("created-from", *createdFrom); err != nil {
+		return err
+	}
+	if filter.CreatedTo, err = parseTimeFlag("created-to", *createdTo); err != nil {
+		return err
+	}
+
+	output := os.Stdout
+	if *out != "" && *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	writer, err := transfer.NewRecordWriter(output, format)
+	if err != nil {
+		return err
+	}
+
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	count, err := transfer.Export(context.Background(), dao.NewUrlAliasDao(dbManager), writer, filter)
+	if err != nil {
+		return err
+	}
+
+	// stdout carries the export, so the summary goes to stderr.
+	fmt.Fprintf(os.Stderr, "exported %d aliases\n", count)
+	return nil
+}
+
+func runAliasImportCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("alias import", flag.ContinueOnError)
+	formatName := flags.String("format", "", "ndjson or csv, derived from the file name if omitted")
+	tenantID := flags.String("tenant", "", "import records without a tenant into this tenant, and reject records of other tenants")
+	conflict := flags.String("conflict", string(dao.ImportConflictSkip), "handling of existing aliases: skip, overwrite or fail")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: alias import [flags] <file|->")
+	}
+	path := flags.Arg(0)
+
+	policy := dao.ImportConflictPolicy(*conflict)
+	if !transfer.IsKnownPolicy(policy) {
+		return fmt.Errorf("--conflict must be one of skip, overwrite or fail")
+	}
+
+	format, err := transferFormat(*formatName, path)
+	if err != nil {
+		return err
+	}
+
+	input := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	reader, err := transfer.NewRecordReader(input, format)
+	if err != nil {
+		return err
+	}
+
+	dbManager, err := initDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	ctx := context.Background()
+	tenantDomains := middleware.NewTenantDomains(dao.NewTenantDao(dbManager))
+	if err := tenantDomains.Refresh(ctx); err != nil {
+		return err
+	}
+	urlPolicy, err := initUrlPolicy(conf, tenantDomains)
+	if err != nil {
+		return err
+	}
+
+	result, err := transfer.Import(ctx, dao.NewUrlAliasDao(dbManager), reader, transfer.ImportOptions{
+		Policy:              policy,
+		TenantID:            *tenantID,
+		Normalizer:          &core.UrlNormalizer{StripTrackingParams: conf.AliasConfig.StripTrackingParams, TrackingParams: conf.AliasConfig.TrackingParams},
+		ReservedAliases:     core.NewReservedAliases(conf.AliasConfig.ReservedAliases),
+		UrlPolicy:           urlPolicy,
+		DefaultRedirectCode: conf.AliasConfig.DefaultRedirectCode,
+	})
+
+	if result != nil {
+		for _, recordErr := range result.Errors {
+			fmt.Fprintf(os.Stderr, "skipped %s\n", recordErr.Error())
+		}
+		if len(result.UpdatedKeys) > 0 {
+			evictAliases(ctx, conf, result.UpdatedKeys)
+		}
+		fmt.Printf("inserted %d, updated %d, skipped %d, failed %d\n", result.Inserted, result.Updated, result.Skipped, result.Failed)
+	}
+	return err
+}
+
+// recomputes the normalized URL of every alias with the current normalization settings. aliases
+// created before normalized URLs were introduced, or before the settings changed, are only
+// found again for equivalent URLs once this ran. redirects use the original URL, so the
+// redirect cache stays valid.
+func runAliasRenormalizeCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("alias renormalize", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("usage: alias renormalize")
+	}
+
+	dbManager, err := initDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	normalizer := &core.UrlNormalizer{StripTrackingParams: conf.AliasConfig.StripTrackingParams, TrackingParams: conf.AliasConfig.TrackingParams}
+	updated, err := dao.NewUrlAliasDao(dbManager).RenormalizeUrlAliases(context.Background(), normalizer.Normalize)
+	fmt.Printf("updated the normalized URL of %d aliases\n", updated)
+	return err
+}
+
+// removes overwritten aliases from the redirect cache. the cache is optional for the CLI,
+// if it can't be reached the stale entries expire on their own.
+func evictAliases(ctx context.Context, conf *config.Config, aliasKeys []string) {
+	cacheManager, err := initRedisCacheManager(ctx, conf, nil)
+	if err == nil {
+		err = cache.DeleteAliasRecords(ctx, cacheManager, aliasKeys...)
+	}
+	if err != nil {
+		log.Printf("Warning: couldn't evict %d overwritten aliases from the cache, they may redirect to their old destination for up to %s: %s",
+			len(aliasKeys), conf.AliasConfig.CacheTTL, err)
+	}
+}
+
+// returns the named format, or the format matching the extension of the file.
+func transferFormat(name string, path string) (transfer.Format, error) {
+	if name != "" {
+		return transfer.ParseFormat(name)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return transfer.FormatCSV, nil
+	}
+	return transfer.FormatNDJSON, nil
+}
+
+// returns nil for an empty value.
+func parseTimeFlag(name string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("--%s must be an RFC3339 timestamp", name)
+	}
+	return &parsed, nil
+}
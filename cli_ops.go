@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/shashwatrathod/url-shortner/internal/cache"
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/db"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/ratelimit"
+)
+
+// cache stores that can be flushed. the click counter stores hold clicks that haven't been
+// persisted yet, so they are left out.
+var flushableCacheStores = []string{cache.ALIAS_CACHE_STORE, ratelimit.RATE_LIMIT_CACHE_STORE}
+
+func runMigrateCommand(conf *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", usage)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	shardRef := flags.String("shard", "", "ID or name of the shard to migrate, every shard if omitted")
+	all := flags.Bool("all", false, "roll back every shard, required by down without --shard")
+	to := flags.Int64("to", -1, "version to roll back to, instead of rolling back the last migration only")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "down" && *shardRef == "" && !*all {
+		return fmt.Errorf("rolling back needs --shard <id|name>, or --all to roll back every shard")
+	}
+
+	// migrations are the point of the command, so they aren't applied on connecting.
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	ctx := context.Background()
+	migrator := initMigrator(conf, dbManager)
+
+	var shardIDs []int
+	if *shardRef != "" {
+		shardID, err := dbManager.FindShardID(*shardRef)
+		if err != nil {
+			return err
+		}
+		shardIDs = []int{shardID}
+	} else {
+		for shardID := 0; shardID < dbManager.ShardCount(); shardID++ {
+			shardIDs = append(shardIDs, shardID)
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		if *shardRef == "" {
+			return migrator.UpAll(ctx)
+		}
+		return migrator.Up(ctx, shardIDs[0])
+
+	case "down":
+		// like migrating up, a failing shard doesn't keep the other shards from being rolled back.
+		var errs []error
+		for _, shardID := range shardIDs {
+			if *to >= 0 {
+				err = migrator.DownTo(ctx, shardID, *to)
+			} else {
+				err = migrator.Down(ctx, shardID)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+
+	case "status":
+		for _, shardID := range shardIDs {
+			status, err := migrator.Status(ctx, shardID)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s: version %d of %d, %d pending\n", status.Shard, status.Version, status.Latest, len(status.Pending))
+			for _, pending := range status.Pending {
+				fmt.Printf("  pending %s\n", pending)
+			}
+		}
+
+		if *shardRef == "" {
+			if _, err := migrator.CheckVersions(ctx); err != nil {
+				fmt.Println(err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown subcommand '%s'\n%s", args[0], usage)
+	}
+}
+
+func runCacheCommand(conf *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", usage)
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "inspect":
+		flags := flag.NewFlagSet("cache inspect", flag.ContinueOnError)
+		tenantID := flags.String("tenant", dao.DefaultTenantID, "tenant of the alias")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: cache inspect [--tenant <id>] <alias>")
+		}
+
+		cacheManager, err := initRedisCacheManager(ctx, conf, nil)
+		if err != nil {
+			return err
+		}
+
+		aliasKey := dao.AliasKey(*tenantID, flags.Arg(0))
+		record, err := cache.GetAliasRecord(ctx, cacheManager, aliasKey)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			fmt.Printf("%s isn't cached\n", aliasKey)
+			return nil
+		}
+
+		encoded, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(encoded))
+		return nil
+
+	case "flush":
+		flags := flag.NewFlagSet("cache flush", flag.ContinueOnError)
+		store := flags.String("store", cache.ALIAS_CACHE_STORE, "store to flush: "+strings.Join(flushableCacheStores, ", "))
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !slices.Contains(flushableCacheStores, *store) {
+			return fmt.Errorf("--store must be one of %s", strings.Join(flushableCacheStores, ", "))
+		}
+
+		cacheManager, err := initRedisCacheManager(ctx, conf, nil)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := cacheManager.Flush(ctx, *store)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("deleted %d keys from %s\n", deleted, *store)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown subcommand '%s'\n%s", args[0], usage)
+	}
+}
+
+// locates the shard from the configuration alone, so that it works while shards are unreachable.
+func runShardCommand(conf *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand\n%s", usage)
+	}
+
+	switch args[0] {
+	case "locate":
+		return runShardLocateCommand(conf, args[1:])
+	case "stamp":
+		return runShardStampCommand(conf, args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand '%s'\n%s", args[0], usage)
+	}
+}
+
+func runShardLocateCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("shard locate", flag.ContinueOnError)
+	tenantID := flags.String("tenant", dao.DefaultTenantID, "tenant of the alias")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: shard locate [--tenant <id>] <alias>")
+	}
+
+	aliasKey := dao.AliasKey(*tenantID, flags.Arg(0))
+	// the shards of the configuration are ordered by shard ID.
+	dbConfig := conf.DBConfigs[db.ShardIndex(aliasKey, len(conf.DBConfigs))]
+
+	fmt.Printf("%s is owned by shard %d (%s) of %d at %s:%d\n", aliasKey, dbConfig.ShardID, dbConfig.DBName, len(conf.DBConfigs), dbConfig.Host, dbConfig.Port)
+	return nil
+}
+
+// records the configured shard IDs and number of shards in the shards, once shards were added
+// or removed. without --yes, it only checks the shards like the server does at startup.
+func runShardStampCommand(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("shard stamp", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "confirm that the aliases were moved to the shards of the configuration")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// the server refuses to start until the shards match, so the CLI connects without checking them.
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	ctx := context.Background()
+	if !*yes {
+		if err := dbManager.VerifyTopology(ctx); err != nil {
+			fmt.Println(err)
+			return fmt.Errorf("aliases are routed by shard ID and number of shards: move them to their new shards first, then run 'shard stamp --yes'")
+		}
+		fmt.Printf("all %d shards match the configuration\n", dbManager.ShardCount())
+		return nil
+	}
+
+	if err := dbManager.StampTopology(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("recorded the configured IDs on all %d shards\n", dbManager.ShardCount())
+	return nil
+}
+
+// loads the configuration like the server does, reporting errors instead of panicking.
+// with --connect the shards and Redis are pinged as well.
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("missing or unknown subcommand\n%s", usage)
+	}
+
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	connect := flags.Bool("connect", false, "also connect to the shards and Redis")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: Error loading .env file, relying on environment variables.")
+	}
+
+	conf, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// the URL policy reads its blocklist file. the tenant domains are only used while checking URLs.
+	if _, err := initUrlPolicy(conf, nil); err != nil {
+		return fmt.Errorf("invalid URL policy: %w", err)
+	}
+
+	fmt.Printf("configuration is valid: %d shards, redis at %s:%d\n", len(conf.DBConfigs), conf.RedisConfig.Host, conf.RedisConfig.Port)
+	if !*connect {
+		return nil
+	}
+
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return err
+	}
+	defer dbManager.CloseAll()
+
+	statuses, err := initMigrator(conf, dbManager).StatusAll(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		fmt.Printf("shard %s: reachable, schema version %d, %d pending migrations\n", status.Shard, status.Version, len(status.Pending))
+	}
+
+	if _, err := initRedisCacheManager(context.Background(), conf, nil); err != nil {
+		return err
+	}
+	fmt.Println("redis: reachable")
+	return nil
+}
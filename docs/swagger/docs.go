@@ -15,7 +15,348 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/anyNonExistentRoute": {
+        "/api/aliases": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the aliases created by the caller on the tenant of the request host, newest first. Callers with the admin scope may pass another owner's subject instead of ` + "`" + `me` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "List owned aliases",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "me",
+                        "description": "Owner of the aliases",
+                        "name": "owner",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of aliases per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as ` + "`" + `nextCursor` + "`" + ` by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "A page of aliases",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListAliasesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Listing another owner's aliases requires the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/aliases/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Exports the aliases of the tenant of the request host as NDJSON or CSV, optionally filtered by creation date. The output can be fed back to the import endpoint.",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export aliases",
+                "parameters": [
+                    {
+                        "enum": [
+                            "ndjson",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "ndjson",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only export aliases created at or after this instant (RFC3339)",
+                        "name": "createdFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only export aliases created before this instant (RFC3339)",
+                        "name": "createdTo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The exported aliases",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/aliases/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Imports aliases exported by the export endpoint into the tenant of the request host. Records of other tenants are rejected. Existing aliases are skipped, overwritten, or abort the import depending on ` + "`" + `conflict` + "`" + `. Invalid records are reported without failing the import.",
+                "consumes": [
+                    "application/x-ndjson",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import aliases",
+                "parameters": [
+                    {
+                        "enum": [
+                            "skip",
+                            "overwrite",
+                            "fail"
+                        ],
+                        "type": "string",
+                        "default": "skip",
+                        "description": "Handling of aliases that already exist",
+                        "name": "conflict",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Aliases as NDJSON or CSV",
+                        "name": "aliases",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of the import",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImportAliasesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters or malformed file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "An alias already exists with different values and the conflict policy is fail",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported content type",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/aliases/{alias}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns total clicks, unique visitors, a click time series, the top referrers, user agents and countries, and near-real-time counters of an alias.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get alias statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL Alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the range (RFC3339). Defaults to 7 days before ` + "`" + `to` + "`" + `.",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the range (RFC3339). Defaults to now.",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "hour",
+                            "day"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Time series bucket size",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of top referrers, user agents and countries",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Statistics of the alias",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AliasStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the read:stats scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Alias not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/anyNonExistentRoute": {
             "get": {
                 "description": "Handles requests for routes that are not found.",
                 "produces": [
@@ -35,7 +376,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/anyRouteWithWrongMethod": {
+        "/api/anyRouteWithWrongMethod": {
             "put": {
                 "description": "Handles requests where the HTTP method is not allowed for the route.",
                 "produces": [
@@ -55,9 +396,17 @@ const docTemplate = `{
                 }
             }
         },
-        "/create": {
+        "/api/create": {
             "post": {
-                "description": "Creates a new URL alias for a given original URL or returns an existing one.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new URL alias owned by the caller for a given original URL, or returns one the caller already owns.",
                 "consumes": [
                     "application/json"
                 ],
@@ -75,21 +424,344 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateUrlAliasRequest"
+                            "$ref": "#/definitions/handlers.CreateUrlAliasRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reused an existing alias",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateUrlAliasResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created a new alias",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateUrlAliasResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload (validation error)",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the create scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Destination URL not allowed by the URL policy",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UrlPolicyErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded, see the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/create/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates or reuses aliases for up to BULK_CREATE_MAX_ITEMS URLs, given as a JSON array of create requests or as a CSV with an ` + "`" + `originalUrl` + "`" + ` column and optional ` + "`" + `redirectCode` + "`" + ` and ` + "`" + `expiresAt` + "`" + ` columns, either as the body or as the ` + "`" + `file` + "`" + ` of a multipart form.",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Create URL aliases in bulk",
+                "parameters": [
+                    {
+                        "description": "URLs to create aliases for",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.CreateUrlAliasRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-item results",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BulkCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed or empty request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the create scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Too many items or request too large",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported content type",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded, see the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/health": {
+            "get": {
+                "description": "Returns the health status of the application.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Application health check",
+                "responses": {
+                    "200": {
+                        "description": "Application is healthy",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Mints a new API key with the given scopes for the tenant of the request's domain. The plaintext key is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Mint an API key",
+                "parameters": [
+                    {
+                        "description": "Request body to mint an API key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateApiKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully minted API key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateApiKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload (validation error)",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the API key with the given id. Revoked keys are rejected immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "API key revoked"
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No active API key with the id",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/{alias}": {
+            "get": {
+                "description": "Deprecated: use /{alias}. Retrieves the original URL for a given alias and redirects to it.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Redirect to original URL (deprecated)",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL Alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "301": {
+                        "description": "Permanently redirects to the original URL (Location header will be set)"
+                    },
+                    "302": {
+                        "description": "Redirects to the original URL (Location header will be set)"
+                    },
+                    "307": {
+                        "description": "Temporarily redirects to the original URL, preserving the method and body"
+                    },
+                    "308": {
+                        "description": "Permanently redirects to the original URL, preserving the method and body"
+                    },
+                    "404": {
+                        "description": "Alias not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Successfully created or retrieved alias",
+                    },
+                    "410": {
+                        "description": "Alias disabled or expired",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateUrlAliasResponse"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Invalid request payload (validation error)",
+                    "429": {
+                        "description": "Rate limit exceeded, see the Retry-After header",
                         "schema": {
-                            "$ref": "#/definitions/middleware.ValidationError"
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
@@ -101,26 +773,6 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Returns the health status of the application.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "health"
-                ],
-                "summary": "Application health check",
-                "responses": {
-                    "200": {
-                        "description": "Application is healthy",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.HealthResponse"
-                        }
-                    }
-                }
-            }
-        },
         "/{alias}": {
             "get": {
                 "description": "Retrieves the original URL for a given alias and redirects to it.",
@@ -141,15 +793,36 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
+                    "301": {
+                        "description": "Permanently redirects to the original URL (Location header will be set)"
+                    },
                     "302": {
                         "description": "Redirects to the original URL (Location header will be set)"
                     },
+                    "307": {
+                        "description": "Temporarily redirects to the original URL, preserving the method and body"
+                    },
+                    "308": {
+                        "description": "Permanently redirects to the original URL, preserving the method and body"
+                    },
                     "404": {
                         "description": "Alias not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "410": {
+                        "description": "Alias disabled or expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded, see the Retry-After header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -161,6 +834,228 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "handlers.AliasListItem": {
+            "description": "A single alias owned by the caller.",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "aBcDeFg1"
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2025-06-24T10:00:00Z"
+                },
+                "expiresAt": {
+                    "description": "Null if the alias never expires",
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "originalUrl": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/url/to/shorten"
+                },
+                "redirectCode": {
+                    "type": "integer",
+                    "example": 302
+                },
+                "shortUrl": {
+                    "type": "string",
+                    "example": "https://sho.rt/aBcDeFg1"
+                }
+            }
+        },
+        "handlers.AliasStatsResponse": {
+            "description": "Click statistics of an alias over a time range.",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "aBcDeFg1"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2025-06-01T00:00:00Z"
+                },
+                "granularity": {
+                    "type": "string",
+                    "example": "day"
+                },
+                "realtime": {
+                    "$ref": "#/definitions/handlers.RealtimeStats"
+                },
+                "rolledUpUntil": {
+                    "description": "Clicks after this instant are not reflected yet, except in ` + "`" + `realtime` + "`" + `",
+                    "type": "string",
+                    "example": "2025-06-07T23:59:00Z"
+                },
+                "timeSeries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TimeSeriesPoint"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2025-06-08T00:00:00Z"
+                },
+                "topCountries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TopValue"
+                    }
+                },
+                "topReferrers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TopValue"
+                    }
+                },
+                "topUserAgents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.TopValue"
+                    }
+                },
+                "totalClicks": {
+                    "type": "integer",
+                    "example": 1234
+                },
+                "uniqueVisitors": {
+                    "type": "integer",
+                    "example": 987
+                }
+            }
+        },
+        "handlers.BulkCreateItemResult": {
+            "description": "Outcome of a single item of a bulk create request. Exactly one of result and error is set.",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/handlers.BulkItemError"
+                },
+                "index": {
+                    "description": "Position of the item in the request, starting at 0",
+                    "type": "integer",
+                    "example": 0
+                },
+                "result": {
+                    "$ref": "#/definitions/handlers.CreateUrlAliasResponse"
+                }
+            }
+        },
+        "handlers.BulkCreateResponse": {
+            "description": "Per-item outcomes of a bulk create request, in request order.",
+            "type": "object",
+            "properties": {
+                "createdCount": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "failedCount": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BulkCreateItemResult"
+                    }
+                },
+                "reusedCount": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.BulkItemError": {
+            "description": "Error of a single item of a bulk request.",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Unprocessable Entity"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Links to private or internal addresses aren't allowed."
+                },
+                "messages": {
+                    "description": "Validation errors, if the item failed validation",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rule": {
+                    "description": "Violated URL policy rule, if the URL wasn't allowed",
+                    "type": "string",
+                    "example": "private_network"
+                }
+            }
+        },
+        "handlers.CreateApiKeyRequest": {
+            "description": "Request body for minting an API key.",
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "campaign-tool"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "create",
+                        "read:stats"
+                    ]
+                }
+            }
+        },
+        "handlers.CreateApiKeyResponse": {
+            "description": "Response body for a minted API key. The key is only returned once.",
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2025-06-21T09:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "Ab3dEf6hIj9k"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "gs_Ab3dEf6hIj9k_0123456789abcdefghijABCDEFGHIJ01"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "campaign-tool"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "create",
+                        "read:stats"
+                    ]
+                },
+                "tenantId": {
+                    "type": "string",
+                    "example": "default"
+                }
+            }
+        },
         "handlers.CreateUrlAliasRequest": {
             "description": "Request body for creating a URL alias.",
             "type": "object",
@@ -168,9 +1063,25 @@ const docTemplate = `{
                 "originalUrl"
             ],
             "properties": {
+                "expiresAt": {
+                    "description": "Instant after which the alias stops redirecting. Must be in the future. Never expires if omitted",
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
                 "originalUrl": {
                     "type": "string",
                     "example": "https://example.com/very/long/url/to/shorten"
+                },
+                "redirectCode": {
+                    "description": "HTTP status used for the redirect. Defaults to the server's configured code (302 unless overridden)",
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ],
+                    "example": 301
                 }
             }
         },
@@ -178,7 +1089,39 @@ const docTemplate = `{
             "description": "Response body for a created URL alias.",
             "type": "object",
             "properties": {
+                "alias": {
+                    "type": "string",
+                    "example": "aBcDeFg1"
+                },
+                "created": {
+                    "description": "False if an existing alias was reused",
+                    "type": "boolean",
+                    "example": true
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2025-06-30T09:00:00Z"
+                },
+                "expiresAt": {
+                    "description": "Null if the alias never expires",
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "originalUrl": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/url/to/shorten"
+                },
+                "redirectCode": {
+                    "type": "integer",
+                    "example": 301
+                },
+                "shortUrl": {
+                    "description": "Fully-qualified short URL on the tenant's domain",
+                    "type": "string",
+                    "example": "https://sho.rt/aBcDeFg1"
+                },
                 "urlAlias": {
+                    "description": "Deprecated: use alias",
                     "type": "string",
                     "example": "aBcDeFg1"
                 }
@@ -210,6 +1153,166 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.ImportAliasesResponse": {
+            "description": "Outcome of an import.",
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Errors of the first 100 failed records",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ImportRecordError"
+                    }
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "inserted": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "skipped": {
+                    "description": "Existing aliases that were kept, and repeated records",
+                    "type": "integer",
+                    "example": 10
+                },
+                "updated": {
+                    "description": "Existing aliases that were overwritten",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "handlers.ImportRecordError": {
+            "description": "A record that couldn't be imported.",
+            "type": "object",
+            "properties": {
+                "line": {
+                    "description": "Line of the record in the uploaded file, starting at 1",
+                    "type": "integer",
+                    "example": 12
+                },
+                "message": {
+                    "type": "string",
+                    "example": "unsupported redirect code 200"
+                }
+            }
+        },
+        "handlers.ListAliasesResponse": {
+            "description": "A page of aliases, newest first.",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AliasListItem"
+                    }
+                },
+                "nextCursor": {
+                    "description": "Pass as ` + "`" + `cursor` + "`" + ` to fetch the next page. Omitted on the last page",
+                    "type": "string",
+                    "example": "MjAyNS0wNi0yNFQxMDowMDowMFp8YUJjRGVGZzE"
+                }
+            }
+        },
+        "handlers.RealtimeStats": {
+            "description": "Near-real-time click numbers, updated on every redirect.",
+            "type": "object",
+            "properties": {
+                "currentHourClicks": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "currentHourUniqueVisitors": {
+                    "type": "integer",
+                    "example": 9
+                },
+                "last24HoursClicks": {
+                    "type": "integer",
+                    "example": 321
+                },
+                "last24HoursUniqueVisitors": {
+                    "type": "integer",
+                    "example": 250
+                },
+                "source": {
+                    "description": "Where the numbers were read from",
+                    "type": "string",
+                    "enum": [
+                        "cache",
+                        "database"
+                    ],
+                    "example": "cache"
+                }
+            }
+        },
+        "handlers.TimeSeriesPoint": {
+            "description": "Clicks within a single hour or day.",
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "2025-06-01T00:00:00Z"
+                },
+                "clicks": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "uniqueVisitors": {
+                    "type": "integer",
+                    "example": 30
+                }
+            }
+        },
+        "handlers.TopValue": {
+            "description": "Number of clicks for a single referrer, user agent or country.",
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer",
+                    "example": 17
+                },
+                "value": {
+                    "type": "string",
+                    "example": "https://news.ycombinator.com/"
+                }
+            }
+        },
+        "handlers.UrlPolicyErrorResponse": {
+            "description": "Error response for a destination URL that isn't allowed.",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Unprocessable Entity"
+                },
+                "field": {
+                    "description": "Request field holding the rejected value",
+                    "type": "string",
+                    "example": "originalUrl"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Links to private or internal addresses aren't allowed."
+                },
+                "rule": {
+                    "description": "Policy rule that was violated",
+                    "type": "string",
+                    "enum": [
+                        "syntax",
+                        "max_length",
+                        "scheme",
+                        "denied_host",
+                        "allowed_host",
+                        "self_reference",
+                        "blocklist",
+                        "private_network"
+                    ],
+                    "example": "private_network"
+                }
+            }
+        },
         "middleware.ValidationError": {
             "description": "Validation error response structure.",
             "type": "object",
@@ -228,6 +1331,19 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT issued by the configured identity provider, as \"Bearer \u003ctoken\u003e\".",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -235,7 +1351,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8080",
-	BasePath:         "/api",
+	BasePath:         "/",
 	Schemes:          []string{"http"},
 	Title:            "URL Shortener API",
 	Description:      "API Documentation for the Go-Short URL shortening service.",
@@ -0,0 +1,318 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/cache"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+const (
+	// keyStore holding the per-hour click counters and the set of counters awaiting a flush.
+	CLICK_COUNTER_CACHE_STORE = "click_counters"
+	// keyStore holding the per-hour unique visitor HyperLogLogs.
+	CLICK_VISITOR_CACHE_STORE = "click_visitors"
+
+	// key of the set tracking "alias|hour" counters that changed since the last flush.
+	dirtyCountersKey = "dirty"
+	// layout of the hour component of counter keys.
+	hourKeyLayout = "2006010215"
+	// how long per-hour counters are kept in the cache.
+	counterTTL = 48 * time.Hour
+	// number of dirty counters processed per flush round trip.
+	flushBatchSize = 500
+	// number of clicks waiting for their counters to be incremented before new clicks are dropped.
+	incrementQueueSize = 10000
+	// number of goroutines incrementing the counters of queued clicks.
+	incrementWorkers = 8
+	// maximum time the increments of a single click are allowed to take.
+	incrementTimeout = 2 * time.Second
+)
+
+// where the real-time numbers were read from.
+const (
+	RealtimeSourceCache    = "cache"
+	RealtimeSourceDatabase = "database"
+)
+
+// RealtimeStats holds near-real-time click numbers of an alias.
+type RealtimeStats struct {
+	CurrentHourClicks         int64
+	CurrentHourUniqueVisitors int64
+	Last24HoursClicks         int64
+	// exact union from the cache. when read from the database this is the
+	// sum of the hourly unique visitors and therefore an upper bound.
+	Last24HoursUniqueVisitors int64
+	Source                    string
+}
+
+type ClickCounter interface {
+	// increments the counters of the event's alias for the hour it happened in.
+	Increment(ctx context.Context, event ClickEvent) error
+
+	// queues the event for Increment by a background worker. never blocks;
+	// the event is dropped if the queue is full or the counter is closed.
+	Record(event ClickEvent)
+
+	// stops accepting events and waits for the queued ones to be counted.
+	Close()
+
+	// returns the real-time numbers of the alias relative to now.
+	GetRealtimeStats(ctx context.Context, alias string, now time.Time) (*RealtimeStats, error)
+
+	// persists every counter that changed since the last flush to the database.
+	Flush(ctx context.Context) error
+}
+
+// cachedClickCounter is a ClickCounter that keeps counters in the CacheManager
+// and periodically persists them to the database.
+type cachedClickCounter struct {
+	cacheManager cache.CacheManager
+	counterDao   dao.AliasClickCounterDao
+	ipHashSalt   string
+
+	mu      sync.RWMutex
+	closed  bool
+	events  chan ClickEvent
+	workers sync.WaitGroup
+}
+
+// creates a ClickCounter and starts the workers incrementing the counters of recorded clicks.
+func NewCachedClickCounter(cacheManager cache.CacheManager, counterDao dao.AliasClickCounterDao, ipHashSalt string) ClickCounter {
+	counter := &cachedClickCounter{
+		cacheManager: cacheManager,
+		counterDao:   counterDao,
+		ipHashSalt:   ipHashSalt,
+		events:       make(chan ClickEvent, incrementQueueSize),
+	}
+
+	for i := 0; i < incrementWorkers; i++ {
+		counter.workers.Add(1)
+		go counter.run()
+	}
+	return counter
+}
+
+func (c *cachedClickCounter) Record(event ClickEvent) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.events <- event:
+	default:
+		log.Printf("click counter queue is full, dropping click for alias '%s'", event.Alias)
+	}
+}
+
+func (c *cachedClickCounter) Close() {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.events)
+	}
+	c.mu.Unlock()
+
+	c.workers.Wait()
+}
+
+// increments the counters of queued events until the queue is closed.
+func (c *cachedClickCounter) run() {
+	defer c.workers.Done()
+
+	for event := range c.events {
+		ctx, cancel := context.WithTimeout(context.Background(), incrementTimeout)
+		if err := c.Increment(ctx, event); err != nil {
+			log.Printf("Error incrementing click counters for alias '%s': %s", event.Alias, err.Error())
+		}
+		cancel()
+	}
+}
+
+func hourKey(alias string, hour time.Time) string {
+	return fmt.Sprintf("%s:%s", alias, hour.UTC().Format(hourKeyLayout))
+}
+
+func (c *cachedClickCounter) Increment(ctx context.Context, event ClickEvent) error {
+	hour := event.ClickedAt.UTC().Truncate(time.Hour)
+	key := hourKey(event.Alias, hour)
+
+	if _, err := c.cacheManager.Increment(ctx, CLICK_COUNTER_CACHE_STORE, key, counterTTL); err != nil {
+		return fmt.Errorf("failed to increment click counter: %w", err)
+	}
+
+	if event.IP != "" {
+		ipHash := hashIP(event.IP, c.ipHashSalt)
+		if err := c.cacheManager.AddToHyperLogLog(ctx, CLICK_VISITOR_CACHE_STORE, key, counterTTL, ipHash); err != nil {
+			return fmt.Errorf("failed to add unique visitor: %w", err)
+		}
+	}
+
+	member := event.Alias + "|" + hour.Format(hourKeyLayout)
+	if err := c.cacheManager.AddToSet(ctx, CLICK_COUNTER_CACHE_STORE, dirtyCountersKey, member); err != nil {
+		return fmt.Errorf("failed to mark click counter dirty: %w", err)
+	}
+	return nil
+}
+
+// reads the numbers from the cache, falling back to the persisted counters
+// if the cache can't be reached.
+func (c *cachedClickCounter) GetRealtimeStats(ctx context.Context, alias string, now time.Time) (*RealtimeStats, error) {
+	stats, err := c.realtimeStatsFromCache(ctx, alias, now)
+	if err == nil {
+		return stats, nil
+	}
+
+	log.Printf("Error reading real-time click counters from cache, falling back to database: %s", err.Error())
+	return c.realtimeStatsFromDatabase(ctx, alias, now)
+}
+
+func (c *cachedClickCounter) realtimeStatsFromCache(ctx context.Context, alias string, now time.Time) (*RealtimeStats, error) {
+	currentHour := now.UTC().Truncate(time.Hour)
+	stats := &RealtimeStats{Source: RealtimeSourceCache}
+
+	keys := make([]string, 0, 24)
+	for i := 0; i < 24; i++ {
+		keys = append(keys, hourKey(alias, currentHour.Add(-time.Duration(i)*time.Hour)))
+	}
+
+	values, err := c.cacheManager.GetMany(ctx, CLICK_COUNTER_CACHE_STORE, keys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		clicks, err := parseCounter(value)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.Last24HoursClicks += clicks
+		if i == 0 {
+			stats.CurrentHourClicks = clicks
+		}
+	}
+
+	if stats.CurrentHourUniqueVisitors, err = c.cacheManager.CountHyperLogLog(ctx, CLICK_VISITOR_CACHE_STORE, keys[0]); err != nil {
+		return nil, err
+	}
+	if stats.Last24HoursUniqueVisitors, err = c.cacheManager.CountHyperLogLog(ctx, CLICK_VISITOR_CACHE_STORE, keys...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (c *cachedClickCounter) realtimeStatsFromDatabase(ctx context.Context, alias string, now time.Time) (*RealtimeStats, error) {
+	currentHour := now.UTC().Truncate(time.Hour)
+
+	counters, err := c.counterDao.GetCounters(ctx, alias, currentHour.Add(-23*time.Hour), currentHour.Add(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &RealtimeStats{Source: RealtimeSourceDatabase}
+	for _, counter := range counters {
+		stats.Last24HoursClicks += counter.Clicks
+		stats.Last24HoursUniqueVisitors += counter.UniqueVisitors
+
+		if counter.Bucket.Equal(currentHour) {
+			stats.CurrentHourClicks = counter.Clicks
+			stats.CurrentHourUniqueVisitors = counter.UniqueVisitors
+		}
+	}
+	return stats, nil
+}
+
+func (c *cachedClickCounter) getCounter(ctx context.Context, key string) (int64, error) {
+	value, err := c.cacheManager.Get(ctx, CLICK_COUNTER_CACHE_STORE, key)
+	if err != nil {
+		return 0, err
+	}
+	return parseCounter(value)
+}
+
+// parses a cached counter value, treating a missing counter as 0.
+func parseCounter(value interface{}) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected click counter value of type %T", value)
+	}
+	return strconv.ParseInt(str, 10, 64)
+}
+
+func (c *cachedClickCounter) Flush(ctx context.Context) error {
+	for {
+		members, err := c.cacheManager.PopFromSet(ctx, CLICK_COUNTER_CACHE_STORE, dirtyCountersKey, flushBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to read dirty click counters: %w", err)
+		}
+
+		if len(members) == 0 {
+			return nil
+		}
+
+		if err := c.flushMembers(ctx, members); err != nil {
+			// put the counters back so the next flush retries them.
+			if reErr := c.cacheManager.AddToSet(ctx, CLICK_COUNTER_CACHE_STORE, dirtyCountersKey, members...); reErr != nil {
+				log.Printf("Error re-queueing %d click counters: %s", len(members), reErr.Error())
+			}
+			return err
+		}
+
+		log.Printf("successfully flushed %d click counters", len(members))
+	}
+}
+
+func (c *cachedClickCounter) flushMembers(ctx context.Context, members []string) error {
+	counters := make([]dao.HourlyClickCounter, 0, len(members))
+
+	for _, member := range members {
+		alias, hourStr, found := strings.Cut(member, "|")
+		if !found {
+			log.Printf("Skipping malformed click counter '%s'", member)
+			continue
+		}
+
+		hour, err := time.Parse(hourKeyLayout, hourStr)
+		if err != nil {
+			log.Printf("Skipping malformed click counter '%s'", member)
+			continue
+		}
+
+		key := hourKey(alias, hour)
+		clicks, err := c.getCounter(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		uniqueVisitors, err := c.cacheManager.CountHyperLogLog(ctx, CLICK_VISITOR_CACHE_STORE, key)
+		if err != nil {
+			return err
+		}
+
+		counters = append(counters, dao.HourlyClickCounter{
+			Alias:          alias,
+			Bucket:         hour,
+			Clicks:         clicks,
+			UniqueVisitors: uniqueVisitors,
+		})
+	}
+
+	if len(counters) == 0 {
+		return nil
+	}
+	return c.counterDao.UpsertCounters(ctx, counters)
+}
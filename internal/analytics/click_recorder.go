@@ -0,0 +1,196 @@
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+// maximum time a single batch insert is allowed to take.
+const flushTimeout = 10 * time.Second
+
+// ClickEvent is a single redirect observed by the service.
+// The raw IP is only kept in memory until the event is processed.
+type ClickEvent struct {
+	Alias     string // Tenant qualified alias key, see dao.AliasKey
+	ClickedAt time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+}
+
+// builds a ClickEvent for the given alias key from the incoming request.
+// clientIP is the address of the client, resolved through any trusted proxies.
+func NewClickEvent(r *http.Request, alias string, clientIP string) ClickEvent {
+	return ClickEvent{
+		Alias:     alias,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP,
+	}
+}
+
+type ClickRecorder interface {
+	// queues the event for asynchronous persistence. never blocks;
+	// the event is dropped if the buffer is full or the recorder is closed.
+	Record(event ClickEvent)
+
+	// stops accepting events and flushes everything that is still buffered.
+	Close() error
+}
+
+type ClickRecorderOptions struct {
+	// number of events that can be buffered before new events are dropped.
+	BufferSize int
+	// maximum number of clicks written in a single insert.
+	BatchSize int
+	// maximum time an event waits in the buffer before being written.
+	FlushInterval time.Duration
+	// salt mixed into client IPs before hashing.
+	IPHashSalt string
+}
+
+// bufferedClickRecorder is a ClickRecorder that batches events in memory
+// and writes them to the database from a background goroutine.
+type bufferedClickRecorder struct {
+	clickDao    dao.AliasClickDao
+	geoIPLookup GeoIPLookup
+	options     ClickRecorderOptions
+
+	mu     sync.RWMutex
+	closed bool
+	events chan ClickEvent
+	done   chan struct{}
+}
+
+// creates a ClickRecorder and starts its background writer.
+func NewBufferedClickRecorder(clickDao dao.AliasClickDao, geoIPLookup GeoIPLookup, options ClickRecorderOptions) ClickRecorder {
+	if geoIPLookup == nil {
+		geoIPLookup = NewNoopGeoIPLookup()
+	}
+
+	recorder := &bufferedClickRecorder{
+		clickDao:    clickDao,
+		geoIPLookup: geoIPLookup,
+		options:     options,
+		events:      make(chan ClickEvent, options.BufferSize),
+		done:        make(chan struct{}),
+	}
+
+	go recorder.run()
+	return recorder
+}
+
+func (b *bufferedClickRecorder) Record(event ClickEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	select {
+	case b.events <- event:
+	default:
+		log.Printf("click buffer is full, dropping click for alias '%s'", event.Alias)
+	}
+}
+
+func (b *bufferedClickRecorder) Close() error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.events)
+	}
+	b.mu.Unlock()
+
+	<-b.done
+	return nil
+}
+
+// consumes events until the channel is closed, writing a batch whenever
+// it is full or the flush interval elapses.
+func (b *bufferedClickRecorder) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]dao.AliasClick, 0, b.options.BatchSize)
+
+	for {
+		select {
+		case event, ok := <-b.events:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+
+			batch = append(batch, b.toAliasClick(event))
+			if len(batch) >= b.options.BatchSize {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+func (b *bufferedClickRecorder) flush(batch []dao.AliasClick) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if err := b.clickDao.InsertClicks(ctx, batch); err != nil {
+		log.Printf("Error writing some of %d clicks: %s", len(batch), err.Error())
+		return
+	}
+	log.Printf("successfully recorded %d clicks", len(batch))
+}
+
+// resolves the country and hashes the IP of the event.
+func (b *bufferedClickRecorder) toAliasClick(event ClickEvent) dao.AliasClick {
+	click := dao.AliasClick{
+		Alias:     event.Alias,
+		ClickedAt: event.ClickedAt,
+		Referrer:  event.Referrer,
+		UserAgent: event.UserAgent,
+	}
+
+	if ip := net.ParseIP(event.IP); ip != nil {
+		country, err := b.geoIPLookup.LookupCountry(ip)
+		if err != nil {
+			log.Printf("Error looking up country for click on alias '%s': %s", event.Alias, err.Error())
+		}
+		if len(country) == 2 {
+			click.Country = country
+		}
+	}
+
+	if event.IP != "" {
+		click.IPHash = hashIP(event.IP, b.options.IPHashSalt)
+	}
+
+	return click
+}
+
+// returns the hex encoded SHA-256 of the salted ip.
+func hashIP(ip string, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ip))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// GeoIPLookup resolves the country an IP address belongs to.
+// Implementations must be safe for concurrent use.
+type GeoIPLookup interface {
+	// returns the ISO 3166-1 alpha-2 country code for the ip,
+	// or an empty string if the country is unknown.
+	LookupCountry(ip net.IP) (string, error)
+}
+
+// noopGeoIPLookup is a GeoIPLookup that never resolves a country.
+type noopGeoIPLookup struct{}
+
+func NewNoopGeoIPLookup() GeoIPLookup {
+	return &noopGeoIPLookup{}
+}
+
+func (n *noopGeoIPLookup) LookupCountry(ip net.IP) (string, error) {
+	return "", nil
+}
+
+type cidrCountry struct {
+	network *net.IPNet
+	country string
+}
+
+// cidrGeoIPLookup is a GeoIPLookup backed by a static list of CIDR ranges.
+type cidrGeoIPLookup struct {
+	ranges []cidrCountry
+}
+
+// creates a GeoIPLookup from a CSV file where every line is of the form "cidr,country".
+// empty lines and lines starting with '#' are ignored.
+func NewCIDRGeoIPLookup(path string) (GeoIPLookup, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var ranges []cidrCountry
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid GeoIP entry on line %d: %q", lineNo, line)
+		}
+
+		_, network, err := net.ParseCIDR(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR on line %d: %w", lineNo, err)
+		}
+
+		ranges = append(ranges, cidrCountry{
+			network: network,
+			country: strings.ToUpper(strings.TrimSpace(parts[1])),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP file %s: %w", path, err)
+	}
+
+	return &cidrGeoIPLookup{ranges: ranges}, nil
+}
+
+func (c *cidrGeoIPLookup) LookupCountry(ip net.IP) (string, error) {
+	for _, r := range c.ranges {
+		if r.network.Contains(ip) {
+			return r.country, nil
+		}
+	}
+	return "", nil
+}
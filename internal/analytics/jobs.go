@@ -0,0 +1,18 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/jobs"
+)
+
+// creates the job that keeps the click rollups up to date.
+func NewRollupJob(statsDao dao.AliasStatsDao, interval time.Duration) *jobs.PeriodicJob {
+	return jobs.NewPeriodicJob("click rollup refresh", interval, 5*time.Minute, statsDao.RefreshRollups)
+}
+
+// creates the job that persists the real-time click counters to the database.
+func NewCounterFlushJob(clickCounter ClickCounter, interval time.Duration) *jobs.PeriodicJob {
+	return jobs.NewPeriodicJob("click counter flush", interval, time.Minute, clickCounter.Flush)
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+const (
+	// prefix of every API key, making leaked keys easy to recognise.
+	apiKeyPrefix = "gs"
+	// length of the public identifier part of a key.
+	apiKeyIDLen = 12
+	// length of the secret part of a key.
+	apiKeySecretLen = 32
+	// character pool containing 0-9, A-Z, a-z (62 characters total)
+	apiKeyCharPool = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// GeneratedAPIKey is a freshly minted API key.
+// Key is only ever shown once; only SecretHash is persisted.
+type GeneratedAPIKey struct {
+	ID         string
+	Key        string
+	SecretHash string
+}
+
+// mints a new API key of the form gs_<id>_<secret>.
+func GenerateAPIKey() (*GeneratedAPIKey, error) {
+	id, err := randomString(apiKeyIDLen)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomString(apiKeySecretLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GeneratedAPIKey{
+		ID:         id,
+		Key:        fmt.Sprintf("%s_%s_%s", apiKeyPrefix, id, secret),
+		SecretHash: HashAPIKeySecret(secret),
+	}, nil
+}
+
+// splits a raw API key into its id and secret.
+func ParseAPIKey(key string) (id string, secret string, err error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || len(parts[1]) != apiKeyIDLen || len(parts[2]) != apiKeySecretLen {
+		return "", "", fmt.Errorf("malformed API key")
+	}
+	return parts[1], parts[2], nil
+}
+
+// returns the hex encoded SHA-256 of the secret.
+// secrets are long random strings, so a fast unsalted hash is sufficient.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// compares the secret against the stored hash in constant time.
+func VerifyAPIKeySecret(secret string, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKeySecret(secret)), []byte(secretHash)) == 1
+}
+
+// returns a cryptographically random string of the given length drawn from apiKeyCharPool.
+func randomString(length int) (string, error) {
+	poolSize := big.NewInt(int64(len(apiKeyCharPool)))
+
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		idx, err := rand.Int(rand.Reader, poolSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random string: %w", err)
+		}
+		sb.WriteByte(apiKeyCharPool[idx.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// mints a new API key of the tenant and stores its hash. the plaintext key is returned
+// alongside the stored record and can't be recovered afterwards.
+func MintAPIKey(ctx context.Context, apiKeyDao dao.ApiKeyDao, name string, scopes []string, tenantID string) (*dao.ApiKey, string, error) {
+	if err := ValidateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+
+	generated, err := GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey, err := apiKeyDao.CreateApiKey(ctx, generated.ID, generated.SecretHash, name, scopes, tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	return apiKey, generated.Key, nil
+}
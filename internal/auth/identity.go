@@ -0,0 +1,29 @@
+package auth
+
+// ways a caller can authenticate.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Identity describes the authenticated caller of a request.
+type Identity struct {
+	// stable identifier of the caller, e.g. "apikey:<id>" or the "sub" claim of a JWT.
+	Subject string
+	// tenant the caller belongs to. credentials that don't name a tenant belong to the default one.
+	TenantID string
+	// how the caller authenticated.
+	Method string
+	// scopes granted to the caller.
+	Scopes []string
+}
+
+// returns true if the identity was granted the scope, either directly or through ScopeAdmin.
+func (i *Identity) HasScope(scope string) bool {
+	for _, granted := range i.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minimum time between two fetches triggered by an unknown key id,
+// so that tokens with bogus key ids can't be used to hammer the identity provider.
+const minJWKSRefreshInterval = 30 * time.Second
+
+// jsonWebKey is a single key of a JSON Web Key Set (RFC 7517).
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// RSA public key parameters
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC public key parameters
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// JWKSKeySource fetches the signing keys published by an identity provider
+// and caches them for a configurable time.
+// keys are fetched without holding the lock guarding the cached keys, so that a slow
+// provider only delays the requests waiting for the keys, and concurrent fetches are
+// merged into one.
+type JWKSKeySource struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+	fetches    singleflight.Group
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewJWKSKeySource(url string, ttl time.Duration, httpClient *http.Client) *JWKSKeySource {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &JWKSKeySource{
+		url:        url,
+		ttl:        ttl,
+		httpClient: httpClient,
+		keys:       make(map[string]crypto.PublicKey),
+	}
+}
+
+// returns the public key with the given key id. the key set is re-fetched
+// when the cache has expired, or when the key id is unknown (to pick up rotated keys).
+func (s *JWKSKeySource) GetKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, found := s.keys[kid]
+	needsRefresh := s.fetchedAt.IsZero() || time.Since(s.fetchedAt) > s.ttl || !found
+	s.mu.RUnlock()
+
+	if needsRefresh {
+		// the fetch is shared by the callers waiting for it, so it isn't cancelled with the request that started it.
+		_, err, _ := s.fetches.Do("", func() (interface{}, error) {
+			return nil, s.refresh(context.WithoutCancel(ctx))
+		})
+		if err != nil {
+			// keep serving the previously fetched keys if the provider is briefly unavailable.
+			log.Printf("Error refreshing JWKS from %s: %s", s.url, err.Error())
+			if !found {
+				return nil, err
+			}
+		}
+
+		s.mu.RLock()
+		key, found = s.keys[kid]
+		s.mu.RUnlock()
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unknown signing key '%s'", kid)
+	}
+	return key, nil
+}
+
+// fetches the key set and replaces the cached keys, unless a fetch was attempted
+// less than minJWKSRefreshInterval ago.
+func (s *JWKSKeySource) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	if now := time.Now(); now.Sub(s.lastAttempt) >= minJWKSRefreshInterval {
+		s.lastAttempt = now
+	} else {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	log.Printf("successfully fetched %d signing keys from %s", len(keys), s.url)
+	return nil
+}
+
+// fetches the key set, returning its signing keys by key id.
+func (s *JWKSKeySource) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", res.StatusCode)
+	}
+
+	var keySet jsonWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&keySet); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key '%s': %s", jwk.Kid, err.Error())
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+// decodes a base64url encoded big-endian unsigned integer.
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter: %w", err)
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
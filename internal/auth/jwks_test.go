@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer serves the public keys of its signing keys as a JWKS, counting the fetches.
+type jwksServer struct {
+	*httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches atomic.Int32
+	// delays every response, to keep fetches in flight.
+	delay time.Duration
+	// status of the responses, 200 if zero.
+	status atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &jwksServer{rsaKey: rsaKey, ecKey: ecKey}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		time.Sleep(s.delay)
+		if status := s.status.Load(); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{
+			{
+				Kid: "rsa-1",
+				Kty: "RSA",
+				Use: "sig",
+				N:   encodeBigInt(rsaKey.N),
+				E:   encodeBigInt(big.NewInt(int64(rsaKey.E))),
+			},
+			{
+				Kid: "ec-1",
+				Kty: "EC",
+				Crv: "P-256",
+				X:   encodeBigInt(ecKey.X),
+				Y:   encodeBigInt(ecKey.Y),
+			},
+			{Kid: "enc-1", Kty: "RSA", Use: "enc", N: encodeBigInt(rsaKey.N), E: "AQAB"},
+		}})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func TestJWKSKeySourceGetKey(t *testing.T) {
+	server := newJWKSServer(t)
+	source := NewJWKSKeySource(server.URL, time.Hour, nil)
+	ctx := context.Background()
+
+	rsaKey, err := source.GetKey(ctx, "rsa-1")
+	if err != nil {
+		t.Fatalf("GetKey(rsa-1) failed: %v", err)
+	}
+	if !server.rsaKey.PublicKey.Equal(rsaKey) {
+		t.Errorf("GetKey(rsa-1) returned another key")
+	}
+
+	ecKey, err := source.GetKey(ctx, "ec-1")
+	if err != nil {
+		t.Fatalf("GetKey(ec-1) failed: %v", err)
+	}
+	if !server.ecKey.PublicKey.Equal(ecKey) {
+		t.Errorf("GetKey(ec-1) returned another key")
+	}
+
+	for _, kid := range []string{"enc-1", "unknown"} {
+		if _, err := source.GetKey(ctx, kid); err == nil {
+			t.Errorf("GetKey(%s) succeeded, want an error", kid)
+		}
+	}
+
+	// unknown key ids don't refetch the key set within minJWKSRefreshInterval.
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("the key set was fetched %d times, want 1", fetches)
+	}
+}
+
+func TestJWKSKeySourceMergesConcurrentFetches(t *testing.T) {
+	server := newJWKSServer(t)
+	server.delay = 100 * time.Millisecond
+	source := NewJWKSKeySource(server.URL, time.Hour, nil)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = source.GetKey(context.Background(), "rsa-1")
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("GetKey of caller %d failed: %v", i, err)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("the key set was fetched %d times, want 1", fetches)
+	}
+}
+
+func TestJWKSKeySourceServesCachedKeysWhileRefreshing(t *testing.T) {
+	server := newJWKSServer(t)
+	source := NewJWKSKeySource(server.URL, time.Hour, nil)
+	ctx := context.Background()
+
+	if _, err := source.GetKey(ctx, "rsa-1"); err != nil {
+		t.Fatalf("GetKey(rsa-1) failed: %v", err)
+	}
+
+	// a slow fetch of an unknown key id doesn't hold up the lookup of cached keys.
+	server.delay = time.Second
+	source.lastAttempt = time.Time{}
+	go source.GetKey(ctx, "rotated")
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := source.GetKey(ctx, "ec-1"); err != nil {
+		t.Fatalf("GetKey(ec-1) failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GetKey(ec-1) waited %s for the refresh", elapsed)
+	}
+}
+
+func TestJWKSKeySourceKeepsKeysWhenProviderFails(t *testing.T) {
+	server := newJWKSServer(t)
+	source := NewJWKSKeySource(server.URL, time.Millisecond, nil)
+	ctx := context.Background()
+
+	if _, err := source.GetKey(ctx, "rsa-1"); err != nil {
+		t.Fatalf("GetKey(rsa-1) failed: %v", err)
+	}
+
+	server.status.Store(http.StatusServiceUnavailable)
+	source.lastAttempt = time.Time{}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := source.GetKey(ctx, "rsa-1"); err != nil {
+		t.Errorf("GetKey(rsa-1) failed once the provider became unavailable: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("the key set was fetched %d times, want 2", fetches)
+	}
+	if _, err := source.GetKey(ctx, "unknown"); err == nil {
+		t.Errorf("GetKey(unknown) succeeded, want an error")
+	}
+}
+
+func TestJWKSJWTVerifierVerify(t *testing.T) {
+	server := newJWKSServer(t)
+	verifier := NewJWKSJWTVerifier(NewJWKSKeySource(server.URL, time.Hour, nil), JWTVerifierOptions{
+		Issuer:      "https://idp.example",
+		Audience:    "url-shortner",
+		TenantClaim: "tenant_id",
+	})
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"iss":       "https://idp.example",
+			"aud":       "url-shortner",
+			"sub":       "user-1",
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"scope":     ScopeCreate + " " + ScopeReadStats,
+			"tenant_id": "acme",
+		}
+		for name, value := range overrides {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"RSA signed", sign(jwt.SigningMethodRS256, "rsa-1", server.rsaKey, claims(nil)), false},
+		{"EC signed", sign(jwt.SigningMethodES256, "ec-1", server.ecKey, claims(nil)), false},
+		{"expired", sign(jwt.SigningMethodRS256, "rsa-1", server.rsaKey, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})), true},
+		{"wrong issuer", sign(jwt.SigningMethodRS256, "rsa-1", server.rsaKey, claims(jwt.MapClaims{"iss": "https://other.example"})), true},
+		{"wrong audience", sign(jwt.SigningMethodRS256, "rsa-1", server.rsaKey, claims(jwt.MapClaims{"aud": "other"})), true},
+		{"key of another kid", sign(jwt.SigningMethodRS256, "ec-1", server.rsaKey, claims(nil)), true},
+		{"unknown kid", sign(jwt.SigningMethodRS256, "rsa-2", server.rsaKey, claims(nil)), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			identity, err := verifier.Verify(context.Background(), test.token)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Verify succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+
+			if identity.Subject != "user-1" || identity.TenantID != "acme" || identity.Method != MethodJWT {
+				t.Errorf("Verify returned %+v", identity)
+			}
+			if !identity.HasScope(ScopeCreate) || identity.HasScope(ScopeAdmin) {
+				t.Errorf("Verify returned the scopes %v", identity.Scopes)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signing algorithms accepted for bearer tokens. symmetric algorithms are
+// deliberately excluded, as the keys come from a public JWKS.
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+type JWTVerifierOptions struct {
+	// expected "iss" claim. not checked if empty.
+	Issuer string
+	// expected "aud" claim. not checked if empty.
+	Audience string
+	// claim holding the tenant of the caller.
+	TenantClaim string
+}
+
+type JWTVerifier interface {
+	// validates the bearer token and maps its claims to an Identity.
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+// jwksJWTVerifier is a JWTVerifier that checks signatures against the keys of a JWKS.
+type jwksJWTVerifier struct {
+	keySource *JWKSKeySource
+	options   JWTVerifierOptions
+	parser    *jwt.Parser
+}
+
+func NewJWKSJWTVerifier(keySource *JWKSKeySource, options JWTVerifierOptions) JWTVerifier {
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtSigningMethods),
+		jwt.WithExpirationRequired(),
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+
+	return &jwksJWTVerifier{
+		keySource: keySource,
+		options:   options,
+		parser:    jwt.NewParser(parserOptions...),
+	}
+}
+
+func (v *jwksJWTVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token has no key id")
+		}
+		return v.keySource.GetKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %w", err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("invalid bearer token: missing subject")
+	}
+
+	identity := &Identity{
+		Subject: subject,
+		Method:  MethodJWT,
+		Scopes:  scopesFromClaims(claims),
+	}
+
+	if v.options.TenantClaim != "" {
+		identity.TenantID, _ = claims[v.options.TenantClaim].(string)
+	}
+
+	return identity, nil
+}
+
+// reads the granted scopes from the space separated "scope" claim (RFC 8693),
+// or from the "scp" claim used by some providers as either a string or a list.
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	switch scp := claims["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package auth
+
+import "fmt"
+
+// scopes that can be granted to API keys.
+const (
+	// allows creating aliases.
+	ScopeCreate = "create"
+	// allows reading alias statistics.
+	ScopeReadStats = "read:stats"
+	// allows everything, including managing API keys.
+	ScopeAdmin = "admin"
+)
+
+// all scopes known to the service.
+var Scopes = []string{ScopeCreate, ScopeReadStats, ScopeAdmin}
+
+// returns an error if any of the scopes is unknown.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !isKnownScope(scope) {
+			return fmt.Errorf("unknown scope '%s'", scope)
+		}
+	}
+	return nil
+}
+
+func isKnownScope(scope string) bool {
+	for _, known := range Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// keyStore holding the cached AliasRecord of every recently resolved alias.
+const ALIAS_CACHE_STORE = "aliases"
+
+// current version of the AliasRecord format. bump it whenever the meaning of
+// an existing field changes; adding optional fields doesn't require a bump.
+const AliasRecordVersion = 1
+
+// redirect status code assumed for records that predate redirect codes.
+const legacyRedirectCode = 302
+
+// AliasRecord is everything the redirect path needs to know about an alias.
+type AliasRecord struct {
+	Version           int        `json:"v"`
+	URL               string     `json:"url"`
+	RedirectCode      int        `json:"code"`
+	ExpiresAt         *time.Time `json:"exp,omitempty"`
+	Disabled          bool       `json:"disabled,omitempty"`
+	PasswordProtected bool       `json:"pw,omitempty"`
+}
+
+// returns true if the record has an expiry that lies before now.
+func (r *AliasRecord) IsExpired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// AliasRecordCodec serializes AliasRecords to and from cache values.
+type AliasRecordCodec interface {
+	Encode(record *AliasRecord) (string, error)
+
+	// decodes a cached value, including values written by older versions of the service.
+	Decode(value string) (*AliasRecord, error)
+}
+
+// jsonAliasRecordCodec is an AliasRecordCodec that stores records as JSON.
+type jsonAliasRecordCodec struct{}
+
+func NewJSONAliasRecordCodec() AliasRecordCodec {
+	return &jsonAliasRecordCodec{}
+}
+
+func (c *jsonAliasRecordCodec) Encode(record *AliasRecord) (string, error) {
+	versioned := *record
+	versioned.Version = AliasRecordVersion
+
+	encoded, err := json.Marshal(&versioned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode alias record: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// besides the current format, it understands the two legacy formats: a bare
+// original URL string and an unversioned {"url": ..., "code": ...} object.
+func (c *jsonAliasRecordCodec) Decode(value string) (*AliasRecord, error) {
+	if value == "" {
+		return nil, fmt.Errorf("empty alias record")
+	}
+
+	if !strings.HasPrefix(value, "{") {
+		return &AliasRecord{URL: value, RedirectCode: legacyRedirectCode}, nil
+	}
+
+	var record AliasRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode alias record: %w", err)
+	}
+
+	if record.Version > AliasRecordVersion {
+		return nil, fmt.Errorf("unsupported alias record version %d", record.Version)
+	}
+
+	if record.URL == "" {
+		return nil, fmt.Errorf("alias record has no URL")
+	}
+
+	if record.RedirectCode == 0 {
+		record.RedirectCode = legacyRedirectCode
+	}
+
+	return &record, nil
+}
+
+// codec used by GetAliasRecord and SetAliasRecord.
+var aliasRecordCodec = NewJSONAliasRecordCodec()
+
+// returns the cached record of the alias, or nil if it isn't cached.
+// undecodable values are reported as errors instead of being returned.
+func GetAliasRecord(ctx context.Context, cm CacheManager, alias string) (*AliasRecord, error) {
+	value, err := cm.Get(ctx, ALIAS_CACHE_STORE, alias)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected alias record of type %T", value)
+	}
+
+	return aliasRecordCodec.Decode(str)
+}
+
+// caches the record of the alias for ttl.
+func SetAliasRecord(ctx context.Context, cm CacheManager, alias string, record *AliasRecord, ttl time.Duration) error {
+	encoded, err := aliasRecordCodec.Encode(record)
+	if err != nil {
+		return err
+	}
+
+	return cm.Set(ctx, ALIAS_CACHE_STORE, alias, encoded, ttl)
+}
+
+// removes the cached records of the aliases, so that their next redirect reads them from the database.
+func DeleteAliasRecords(ctx context.Context, cm CacheManager, aliases ...string) error {
+	return cm.Delete(ctx, ALIAS_CACHE_STORE, aliases...)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONAliasRecordCodecDecodesLegacyValues(t *testing.T) {
+	codec := NewJSONAliasRecordCodec()
+
+	tests := []struct {
+		name  string
+		value string
+		want  AliasRecord
+	}{
+		{"bare URL", "https://example.com/a", AliasRecord{URL: "https://example.com/a", RedirectCode: 302}},
+		{"unversioned object", `{"url":"https://example.com/a"}`, AliasRecord{URL: "https://example.com/a", RedirectCode: 302}},
+		{"unversioned object with a code", `{"url":"https://example.com/a","code":301}`, AliasRecord{URL: "https://example.com/a", RedirectCode: 301}},
+		{"unknown fields", `{"v":1,"url":"https://example.com/a","code":307,"new":true}`, AliasRecord{Version: 1, URL: "https://example.com/a", RedirectCode: 307}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record, err := codec.Decode(test.value)
+			if err != nil {
+				t.Fatalf("Decode(%q) failed: %v", test.value, err)
+			}
+			if !reflect.DeepEqual(*record, test.want) {
+				t.Errorf("Decode(%q) = %+v, want %+v", test.value, *record, test.want)
+			}
+		})
+	}
+}
+
+func TestJSONAliasRecordCodecRejectsInvalidValues(t *testing.T) {
+	codec := NewJSONAliasRecordCodec()
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"newer version", `{"v":2,"url":"https://example.com/a","code":302}`, "unsupported alias record version 2"},
+		{"empty", "", "empty alias record"},
+		{"no URL", `{"v":1,"code":302}`, "no URL"},
+		{"invalid JSON", `{"url":`, "failed to decode"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record, err := codec.Decode(test.value)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Decode(%q) = %+v, %v, want an error containing %q", test.value, record, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONAliasRecordCodecRoundTrip(t *testing.T) {
+	codec := NewJSONAliasRecordCodec()
+	expiresAt := time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)
+	record := &AliasRecord{URL: "https://example.com/a", RedirectCode: 308, ExpiresAt: &expiresAt, Disabled: true, PasswordProtected: true}
+
+	encoded, err := codec.Encode(record)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decoded, err := codec.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode(%q) failed: %v", encoded, err)
+	}
+
+	want := *record
+	want.Version = AliasRecordVersion
+	if !reflect.DeepEqual(*decoded, want) {
+		t.Errorf("Decode(Encode(%+v)) = %+v, want %+v", *record, *decoded, want)
+	}
+	if record.Version != 0 {
+		t.Errorf("Encode changed the version of the record to %d", record.Version)
+	}
+}
@@ -13,15 +13,46 @@ import (
 const DEFAULT_EXPIRY_SECONDS time.Duration = time.Duration(20) * time.Minute
 
 type CacheManager interface {
-	// Sets the key with the given value with DEFAULT_EXPIRY_SECONDS.
+	// Sets the key with the given value, expiring after ttl, or DEFAULT_EXPIRY_SECONDS if ttl isn't positive.
 	// keyStore identifies the bucket where the key is to be stored.
 	// for example key=myKey set in the fooStore would not be found in barStore.
 	// Overrides the value and resets the time if the key already exists.
-	Set(ctx context.Context, keyStore string, key string, value interface{}) error
+	Set(ctx context.Context, keyStore string, key string, value interface{}, ttl time.Duration) error
 
 	// Gets the value for the given key from the given keyStore.
 	// Returns nil if the key doesn't exist or is expired.
 	Get(ctx context.Context, keyStore string, key string) (interface{}, error)
+
+	// Gets the values of the keys from the given keyStore in one round trip, in the order of the keys.
+	// Values of keys that don't exist or are expired are nil.
+	GetMany(ctx context.Context, keyStore string, keys ...string) ([]interface{}, error)
+
+	// Increments the integer value of the key by one and returns the new value.
+	// A missing key is treated as 0. If ttl is positive, the expiry of the key is reset to ttl.
+	Increment(ctx context.Context, keyStore string, key string, ttl time.Duration) (int64, error)
+
+	// Like Increment, but increments the value by delta.
+	IncrementBy(ctx context.Context, keyStore string, key string, delta int64, ttl time.Duration) (int64, error)
+
+	// Adds the values to the HyperLogLog stored at the key, creating it if needed.
+	// If ttl is positive, the expiry of the key is reset to ttl.
+	AddToHyperLogLog(ctx context.Context, keyStore string, key string, ttl time.Duration, values ...string) error
+
+	// Returns the approximate number of unique values across the HyperLogLogs
+	// stored at the given keys. Missing keys count as empty.
+	CountHyperLogLog(ctx context.Context, keyStore string, keys ...string) (int64, error)
+
+	// Adds the members to the set stored at the key.
+	AddToSet(ctx context.Context, keyStore string, key string, members ...string) error
+
+	// Removes and returns up to count random members of the set stored at the key.
+	PopFromSet(ctx context.Context, keyStore string, key string, count int64) ([]string, error)
+
+	// Deletes the keys from the given keyStore. Missing keys are ignored.
+	Delete(ctx context.Context, keyStore string, keys ...string) error
+
+	// Deletes every key of the given keyStore and returns how many were deleted.
+	Flush(ctx context.Context, keyStore string) (int64, error)
 }
 
 // redisCacheManager is a CacheManager that uses Redis as its cache management engine.
@@ -44,9 +75,24 @@ func (r *redisCacheManager) Get(ctx context.Context, keyStore string, key string
 	return res, nil
 }
 
-func (r *redisCacheManager) Set(ctx context.Context, keyStore string, key string, value interface{}) error {
+func (r *redisCacheManager) GetMany(ctx context.Context, keyStore string, keys ...string) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	ks := make([]string, len(keys))
+	for i, key := range keys {
+		ks[i] = fmt.Sprintf("%s:%s", keyStore, key)
+	}
+	return r.client.MGet(ctx, ks...).Result()
+}
+
+func (r *redisCacheManager) Set(ctx context.Context, keyStore string, key string, value interface{}, ttl time.Duration) error {
 	k := fmt.Sprintf("%s:%s", keyStore, key)
-	res, err := r.client.Set(ctx, k, value, DEFAULT_EXPIRY_SECONDS).Result()
+	if ttl <= 0 {
+		ttl = DEFAULT_EXPIRY_SECONDS
+	}
+	res, err := r.client.Set(ctx, k, value, ttl).Result()
 
 	if err != nil {
 		return err
@@ -60,6 +106,116 @@ func (r *redisCacheManager) Set(ctx context.Context, keyStore string, key string
 	return nil
 }
 
+func (r *redisCacheManager) Increment(ctx context.Context, keyStore string, key string, ttl time.Duration) (int64, error) {
+	return r.IncrementBy(ctx, keyStore, key, 1, ttl)
+}
+
+func (r *redisCacheManager) IncrementBy(ctx context.Context, keyStore string, key string, delta int64, ttl time.Duration) (int64, error) {
+	k := fmt.Sprintf("%s:%s", keyStore, key)
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, k, delta)
+	if ttl > 0 {
+		pipe.Expire(ctx, k, ttl)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (r *redisCacheManager) AddToHyperLogLog(ctx context.Context, keyStore string, key string, ttl time.Duration, values ...string) error {
+	k := fmt.Sprintf("%s:%s", keyStore, key)
+
+	elements := make([]interface{}, len(values))
+	for i, value := range values {
+		elements[i] = value
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.PFAdd(ctx, k, elements...)
+	if ttl > 0 {
+		pipe.Expire(ctx, k, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *redisCacheManager) CountHyperLogLog(ctx context.Context, keyStore string, keys ...string) (int64, error) {
+	ks := make([]string, len(keys))
+	for i, key := range keys {
+		ks[i] = fmt.Sprintf("%s:%s", keyStore, key)
+	}
+
+	return r.client.PFCount(ctx, ks...).Result()
+}
+
+func (r *redisCacheManager) AddToSet(ctx context.Context, keyStore string, key string, members ...string) error {
+	k := fmt.Sprintf("%s:%s", keyStore, key)
+
+	elements := make([]interface{}, len(members))
+	for i, member := range members {
+		elements[i] = member
+	}
+
+	return r.client.SAdd(ctx, k, elements...).Err()
+}
+
+func (r *redisCacheManager) PopFromSet(ctx context.Context, keyStore string, key string, count int64) ([]string, error) {
+	k := fmt.Sprintf("%s:%s", keyStore, key)
+
+	members, err := r.client.SPopN(ctx, k, count).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *redisCacheManager) Delete(ctx context.Context, keyStore string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ks := make([]string, len(keys))
+	for i, key := range keys {
+		ks[i] = fmt.Sprintf("%s:%s", keyStore, key)
+	}
+
+	return r.client.Del(ctx, ks...).Err()
+}
+
+// keys are found with SCAN rather than KEYS, so that flushing a large store doesn't block Redis.
+func (r *redisCacheManager) Flush(ctx context.Context, keyStore string) (int64, error) {
+	var deleted int64
+	iter := r.client.Scan(ctx, 0, fmt.Sprintf("%s:*", keyStore), 1000).Iterator()
+
+	batch := make([]string, 0, 1000)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := r.client.Del(ctx, batch...).Result()
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
+
 func NewRedisCacheManager(ctx context.Context, client *redis.Client) (CacheManager, error) {
 
 	if client == nil {
@@ -1,18 +1,57 @@
 package config
 
 import (
-	"fmt"
+	"net"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/utils"
 )
 
-type DBConfig struct {
-	Host     string
-	Port     int
-	DBName   string
-	DBUser   string
-	Password string
+type ReplicaConfig struct {
+	// how often replicas are checked to be reachable and caught up.
+	HealthCheckInterval time.Duration
+	// replicas lagging further behind their primary stop serving reads until they catch up.
+	MaxLag time.Duration
+}
+
+// levels of the request log.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+type LogConfig struct {
+	// minimum level of the requests that are logged, one of the LogLevel constants.
+	Level string
+}
+
+type ReloadConfig struct {
+	// config file the configuration was loaded from, if any.
+	File string
+	// how often the config file is checked for changes. 0 only reloads on SIGHUP.
+	WatchInterval time.Duration
+}
+
+type SecretsConfig struct {
+	// files secrets were read from, e.g. through REDIS_PASSWORD_FILE.
+	Files []string
+	// how often the secret files are read again to pick up rotated credentials.
+	RefreshInterval time.Duration
+}
+
+type MigrationConfig struct {
+	// applies pending migrations to every shard at startup.
+	AutoMigrate bool
+	// optional directory to read migrations from instead of the ones embedded in the binary.
+	Dir string
+	// maximum time to wait for another instance to finish migrating a shard.
+	LockTimeout time.Duration
 }
 
 type RedisConfig struct {
@@ -21,98 +60,405 @@ type RedisConfig struct {
 	Password string
 }
 
+type AnalyticsConfig struct {
+	// number of click events buffered in memory before new ones are dropped.
+	BufferSize int
+	// maximum number of clicks written to a shard in one insert.
+	BatchSize int
+	// maximum time a click waits in the buffer before being written.
+	FlushInterval time.Duration
+	// salt mixed into client IPs before they are hashed and stored.
+	IPHashSalt string
+	// optional path to a "cidr,country" CSV file used to resolve click countries.
+	GeoIPFile string
+	// how often raw clicks are aggregated into the rollup tables.
+	RollupInterval time.Duration
+	// how often the real-time click counters are persisted from the cache to the database.
+	CounterFlushInterval time.Duration
+}
+
+type AliasConfig struct {
+	// redirect status code used for aliases created without an explicit one.
+	DefaultRedirectCode int
+	// scheme and host short URLs of the default tenant are built with, e.g. "https://sho.rt".
+	// the host of the request is used if empty.
+	PublicBaseURL string
+	// aliases that can't be created, in addition to the paths served by the service itself.
+	ReservedAliases []string
+	// removes tracking parameters when normalizing destination URLs, so that URLs only
+	// differing in them share an alias.
+	StripTrackingParams bool
+	// tracking parameters removed if StripTrackingParams is set. entries ending with '*' are prefixes.
+	TrackingParams []string
+	// maximum number of URLs accepted by one bulk create request.
+	BulkCreateMaxItems int
+	// how long resolved aliases are cached for redirects. changes to an alias made outside
+	// the service are only seen once its cache entry expires.
+	CacheTTL time.Duration
+}
+
+type AuthConfig struct {
+	// URL of the identity provider's JWKS. bearer tokens are rejected if empty.
+	JWKSURL string
+	// how long fetched signing keys are cached.
+	JWKSCacheTTL time.Duration
+	// expected "iss" claim of bearer tokens. not checked if empty.
+	JWTIssuer string
+	// expected "aud" claim of bearer tokens. not checked if empty.
+	JWTAudience string
+	// claim holding the tenant of the caller.
+	JWTTenantClaim string
+}
+
+type TenantConfig struct {
+	// how long the tenant of a request host is cached in memory.
+	CacheTTL time.Duration
+	// maximum number of hosts cached in memory.
+	CacheMaxEntries int
+}
+
+type ServerConfig struct {
+	// reverse proxies whose X-Forwarded-For header is used to determine client IPs.
+	TrustedProxies utils.TrustedProxies
+}
+
+type RateLimitConfig struct {
+	// requests allowed per caller within CreateWindow on the create endpoint. 0 disables the limit.
+	CreateLimit  int
+	CreateWindow time.Duration
+	// requests allowed per client IP within RedirectWindow on the redirect routes. 0 disables the limit.
+	RedirectLimit  int
+	RedirectWindow time.Duration
+}
+
+type UrlPolicyConfig struct {
+	// schemes destination URLs may use.
+	AllowedSchemes []string
+	// if not empty, destination URLs must point to one of these domains or their subdomains.
+	AllowedHosts []string
+	// domains, including their subdomains, destination URLs may not point to.
+	DeniedHosts []string
+	// rejects destination URLs pointing to loopback, private or link-local addresses.
+	BlockPrivateNetworks bool
+	// maximum length of destination URLs.
+	MaxURLLength int
+	// optional path to a file with one blocked domain per line.
+	BlocklistFile string
+}
+
 type Config struct {
-	DBConfigs   []DBConfig
-	RedisConfig RedisConfig
+	DBConfigs       []DBConfig
+	MigrationConfig MigrationConfig
+	ReplicaConfig   ReplicaConfig
+	RedisConfig     RedisConfig
+	AnalyticsConfig AnalyticsConfig
+	AliasConfig     AliasConfig
+	AuthConfig      AuthConfig
+	TenantConfig    TenantConfig
+	ServerConfig    ServerConfig
+	RateLimitConfig RateLimitConfig
+	UrlPolicyConfig UrlPolicyConfig
+	SecretsConfig   SecretsConfig
+	LogConfig       LogConfig
+	ReloadConfig    ReloadConfig
 }
 
-// Load reads database configuration from environment variables and returns a Config instance.
-// It parses comma-separated values from multiple environment variables to configure database connections.
+// Load reads the configuration from environment variables and returns a Config instance.
 //
-// The function will panic if:
-//   - Environment variables contain invalid data
-//   - Configuration values are inconsistent or missing
+// If CONFIG_FILE names a YAML file, it provides the shards and the values of the settings
+// that aren't set in the environment; CONFIG_PROFILE selects one of its profiles.
 //
-// Returns a pointer to a Config struct containing the parsed database configurations.
+// Every invalid or inconsistent setting is reported in the returned *ValidationError.
 func Load() (*Config, error) {
-	dbConfigs, err := loadDBConfigs()
-
+	file, err := loadFile(os.Getenv("CONFIG_FILE"), os.Getenv("CONFIG_PROFILE"))
 	if err != nil {
 		return nil, err
 	}
 
-	redisConfig, err := loadRedisConfig()
-	if err != nil {
-		return nil, err
+	var fileSettings map[string]string
+	var fileShards []fileShard
+	if file != nil {
+		fileSettings = file.Settings
+		fileShards = file.Shards
+	}
+
+	s := newSettings(fileSettings)
+	if file != nil {
+		s.errs = append(s.errs, file.errs...)
 	}
 
-	return &Config{
-		DBConfigs:   dbConfigs,
-		RedisConfig: *redisConfig,
-	}, nil
+	conf := &Config{
+		DBConfigs:       loadDBConfigs(s, fileShards),
+		MigrationConfig: loadMigrationConfig(s),
+		ReplicaConfig:   loadReplicaConfig(s),
+		RedisConfig:     loadRedisConfig(s),
+		AnalyticsConfig: loadAnalyticsConfig(s),
+		AliasConfig:     loadAliasConfig(s),
+		AuthConfig:      loadAuthConfig(s),
+		TenantConfig:    loadTenantConfig(s),
+		ServerConfig:    loadServerConfig(s),
+		RateLimitConfig: loadRateLimitConfig(s),
+		UrlPolicyConfig: loadUrlPolicyConfig(s),
+		LogConfig:       loadLogConfig(s),
+		ReloadConfig:    loadReloadConfig(s),
+	}
+	// secrets are read by the other sections, so their files are known last.
+	conf.SecretsConfig = loadSecretsConfig(s)
+
+	s.checkUnknown()
+	if len(s.errs) > 0 {
+		return nil, &ValidationError{Errors: s.errs}
+	}
+	return conf, nil
 }
 
-func loadRedisConfig() (*RedisConfig, error) {
-	host := strings.TrimSpace(os.Getenv("REDIS_HOST"))
-	portString := strings.TrimSpace(os.Getenv("REDIS_PORT"))
-	password := strings.TrimSpace(os.Getenv("REDIS_PASSWORD"))
+func loadLogConfig(s *settings) LogConfig {
+	level := strings.ToLower(s.get("LOG_LEVEL"))
+	switch level {
+	case "":
+		level = LogLevelInfo
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		s.errorf("LOG_LEVEL must be one of debug, info, warn or error")
+	}
 
-	if host == "" {
-		host = "localhost"
+	return LogConfig{
+		Level: level,
 	}
+}
 
-	if portString == "" {
-		portString = "6379"
+func loadReloadConfig(s *settings) ReloadConfig {
+	watchInterval := s.duration("CONFIG_WATCH_INTERVAL", 10*time.Second)
+	if watchInterval < 0 {
+		s.errorf("CONFIG_WATCH_INTERVAL can't be negative")
 	}
 
-	portInt, err := strconv.Atoi(portString)
-	if err != nil {
-		return nil, err
+	return ReloadConfig{
+		File:          strings.TrimSpace(os.Getenv("CONFIG_FILE")),
+		WatchInterval: watchInterval,
 	}
+}
 
-	return &RedisConfig{
-		Host:     host,
-		Port:     portInt,
-		Password: password,
-	}, nil
-}
-
-func loadDBConfigs() ([]DBConfig, error) {
-	envHosts := os.Getenv("DB_HOST_LIST")
-	envPorts := os.Getenv("DB_PORT_LIST")
-	envNames := os.Getenv("DB_NAME_LIST")
-	envUsers := os.Getenv("DB_USER_LIST")
-	envPasswords := os.Getenv("DB_PASSWORD_LIST")
-
-	hosts := strings.Split(envHosts, ",")
-	portStrings := strings.Split(envPorts, ",")
-	ports := make([]int, len(portStrings))
-	for i, port := range portStrings {
-		portInt, err := strconv.Atoi(strings.TrimSpace(port))
+func loadSecretsConfig(s *settings) SecretsConfig {
+	refreshInterval := s.duration("SECRETS_REFRESH_INTERVAL", time.Minute)
+	if refreshInterval <= 0 {
+		s.errorf("SECRETS_REFRESH_INTERVAL must be positive")
+	}
+
+	return SecretsConfig{
+		Files:           s.secretFiles,
+		RefreshInterval: refreshInterval,
+	}
+}
+
+func loadMigrationConfig(s *settings) MigrationConfig {
+	lockTimeout := s.duration("DB_MIGRATION_LOCK_TIMEOUT", 5*time.Minute)
+	if lockTimeout < time.Second {
+		s.errorf("DB_MIGRATION_LOCK_TIMEOUT must be at least 1s")
+	}
+
+	return MigrationConfig{
+		AutoMigrate: s.bool("DB_AUTO_MIGRATE", true),
+		Dir:         s.get("DB_MIGRATION_DIR"),
+		LockTimeout: lockTimeout,
+	}
+}
+
+func loadReplicaConfig(s *settings) ReplicaConfig {
+	healthCheckInterval := s.duration("DB_REPLICA_HEALTH_CHECK_INTERVAL", 5*time.Second)
+	if healthCheckInterval <= 0 {
+		s.errorf("DB_REPLICA_HEALTH_CHECK_INTERVAL must be positive")
+	}
+
+	return ReplicaConfig{
+		HealthCheckInterval: healthCheckInterval,
+		MaxLag:              s.duration("DB_REPLICA_MAX_LAG", 10*time.Second),
+	}
+}
+
+func loadUrlPolicyConfig(s *settings) UrlPolicyConfig {
+	maxURLLength := s.int("URL_POLICY_MAX_LENGTH", 2048)
+	if maxURLLength <= 0 {
+		s.errorf("URL_POLICY_MAX_LENGTH must be positive.")
+	}
+
+	allowedSchemes := s.list("URL_POLICY_ALLOWED_SCHEMES")
+	if len(allowedSchemes) == 0 {
+		allowedSchemes = []string{"http", "https"}
+	}
+
+	return UrlPolicyConfig{
+		AllowedSchemes:       allowedSchemes,
+		AllowedHosts:         s.list("URL_POLICY_ALLOWED_HOSTS"),
+		DeniedHosts:          s.list("URL_POLICY_DENIED_HOSTS"),
+		BlockPrivateNetworks: s.bool("URL_POLICY_BLOCK_PRIVATE_NETWORKS", true),
+		MaxURLLength:         maxURLLength,
+		BlocklistFile:        s.get("URL_POLICY_BLOCKLIST_FILE"),
+	}
+}
+
+func loadServerConfig(s *settings) ServerConfig {
+	var trustedProxies utils.TrustedProxies
+	for _, value := range s.list("TRUSTED_PROXIES") {
+		// single addresses are accepted as well as CIDRs.
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(value)
 		if err != nil {
-			return make([]DBConfig, 0), err
+			s.errorf("TRUSTED_PROXIES must be a comma-separated list of IPs or CIDRs: %w", err)
+			continue
 		}
-		ports[i] = portInt
+		trustedProxies = append(trustedProxies, network)
 	}
 
-	names := strings.Split(envNames, ",")
-	users := strings.Split(envUsers, ",")
-	passwords := strings.Split(envPasswords, ",")
+	return ServerConfig{
+		TrustedProxies: trustedProxies,
+	}
+}
+
+func loadRateLimitConfig(s *settings) RateLimitConfig {
+	createLimit := s.int("RATE_LIMIT_CREATE_LIMIT", 60)
+	createWindow := s.duration("RATE_LIMIT_CREATE_WINDOW", time.Minute)
+	redirectLimit := s.int("RATE_LIMIT_REDIRECT_LIMIT", 600)
+	redirectWindow := s.duration("RATE_LIMIT_REDIRECT_WINDOW", time.Minute)
+
+	if createLimit < 0 || redirectLimit < 0 {
+		s.errorf("RATE_LIMIT_CREATE_LIMIT and RATE_LIMIT_REDIRECT_LIMIT can't be negative.")
+	}
 
-	if len(hosts) != len(ports) || len(hosts) != len(names) || len(hosts) != len(users) || len(hosts) != len(passwords) {
-		return make([]DBConfig, 0), fmt.Errorf("Environment variables for database configuration are not consistent in length. Please ensure DB_HOST_LIST, DB_PORT_LIST, DB_NAME_LIST, DB_USER_LIST, and DB_PASSWORD_LIST are set correctly.")
+	if createWindow < time.Second || redirectWindow < time.Second {
+		s.errorf("RATE_LIMIT_CREATE_WINDOW and RATE_LIMIT_REDIRECT_WINDOW must be at least 1s.")
 	}
 
-	dbConfigs := make([]DBConfig, len(hosts))
-	for i := range hosts {
-		dbConfigs[i] = DBConfig{
-			Host:     strings.TrimSpace(hosts[i]),
-			Port:     ports[i],
-			DBName:   strings.TrimSpace(names[i]),
-			DBUser:   strings.TrimSpace(users[i]),
-			Password: strings.TrimSpace(passwords[i]),
+	return RateLimitConfig{
+		CreateLimit:    createLimit,
+		CreateWindow:   createWindow,
+		RedirectLimit:  redirectLimit,
+		RedirectWindow: redirectWindow,
+	}
+}
+
+func loadTenantConfig(s *settings) TenantConfig {
+	cacheTTL := s.duration("TENANT_CACHE_TTL", time.Minute)
+	if cacheTTL <= 0 {
+		s.errorf("TENANT_CACHE_TTL must be positive.")
+	}
+	cacheMaxEntries := s.int("TENANT_CACHE_MAX_ENTRIES", 10000)
+	if cacheMaxEntries <= 0 {
+		s.errorf("TENANT_CACHE_MAX_ENTRIES must be positive.")
+	}
+
+	return TenantConfig{
+		CacheTTL:        cacheTTL,
+		CacheMaxEntries: cacheMaxEntries,
+	}
+}
+
+func loadAuthConfig(s *settings) AuthConfig {
+	tenantClaim := s.get("AUTH_JWT_TENANT_CLAIM")
+	if tenantClaim == "" {
+		tenantClaim = "tenant_id"
+	}
+
+	return AuthConfig{
+		JWKSURL:        s.get("AUTH_JWKS_URL"),
+		JWKSCacheTTL:   s.duration("AUTH_JWKS_CACHE_TTL", 15*time.Minute),
+		JWTIssuer:      s.get("AUTH_JWT_ISSUER"),
+		JWTAudience:    s.get("AUTH_JWT_AUDIENCE"),
+		JWTTenantClaim: tenantClaim,
+	}
+}
+
+// redirect status codes an alias can be configured with.
+var SupportedRedirectCodes = []int{301, 302, 307, 308}
+
+// returns true if the code is one of SupportedRedirectCodes.
+func IsSupportedRedirectCode(code int) bool {
+	for _, supported := range SupportedRedirectCodes {
+		if code == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func loadAliasConfig(s *settings) AliasConfig {
+	defaultRedirectCode := s.int("DEFAULT_REDIRECT_CODE", 302)
+	if !IsSupportedRedirectCode(defaultRedirectCode) {
+		s.errorf("DEFAULT_REDIRECT_CODE must be one of 301, 302, 307 or 308.")
+	}
+
+	publicBaseURL := strings.TrimRight(s.get("PUBLIC_BASE_URL"), "/")
+	if publicBaseURL != "" {
+		parsed, err := url.Parse(publicBaseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Path != "" {
+			s.errorf("PUBLIC_BASE_URL must be an http(s) URL without a path, e.g. https://sho.rt.")
 		}
 	}
 
-	return dbConfigs, nil
+	bulkCreateMaxItems := s.int("BULK_CREATE_MAX_ITEMS", 1000)
+	if bulkCreateMaxItems <= 0 {
+		s.errorf("BULK_CREATE_MAX_ITEMS must be positive.")
+	}
+
+	cacheTTL := s.duration("ALIAS_CACHE_TTL", 20*time.Minute)
+	if cacheTTL <= 0 {
+		s.errorf("ALIAS_CACHE_TTL must be positive.")
+	}
+
+	trackingParams := s.list("URL_NORMALIZE_TRACKING_PARAMS")
+	if len(trackingParams) == 0 {
+		trackingParams = core.DefaultTrackingParams
+	}
+
+	return AliasConfig{
+		DefaultRedirectCode: defaultRedirectCode,
+		PublicBaseURL:       publicBaseURL,
+		ReservedAliases:     s.list("RESERVED_ALIASES"),
+		StripTrackingParams: s.bool("URL_NORMALIZE_STRIP_TRACKING_PARAMS", false),
+		TrackingParams:      trackingParams,
+		BulkCreateMaxItems:  bulkCreateMaxItems,
+		CacheTTL:            cacheTTL,
+	}
+}
+
+func loadAnalyticsConfig(s *settings) AnalyticsConfig {
+	bufferSize := s.int("ANALYTICS_BUFFER_SIZE", 10000)
+	batchSize := s.int("ANALYTICS_BATCH_SIZE", 500)
+	flushInterval := s.duration("ANALYTICS_FLUSH_INTERVAL", 5*time.Second)
+	rollupInterval := s.duration("ANALYTICS_ROLLUP_INTERVAL", time.Minute)
+	counterFlushInterval := s.duration("ANALYTICS_COUNTER_FLUSH_INTERVAL", 30*time.Second)
+
+	if bufferSize <= 0 || batchSize <= 0 || flushInterval <= 0 || rollupInterval <= 0 || counterFlushInterval <= 0 {
+		s.errorf("ANALYTICS_BUFFER_SIZE, ANALYTICS_BATCH_SIZE, ANALYTICS_FLUSH_INTERVAL, ANALYTICS_ROLLUP_INTERVAL and ANALYTICS_COUNTER_FLUSH_INTERVAL must be positive.")
+	}
+
+	return AnalyticsConfig{
+		BufferSize:           bufferSize,
+		BatchSize:            batchSize,
+		FlushInterval:        flushInterval,
+		IPHashSalt:           s.secret("ANALYTICS_IP_HASH_SALT"),
+		GeoIPFile:            s.get("ANALYTICS_GEOIP_FILE"),
+		RollupInterval:       rollupInterval,
+		CounterFlushInterval: counterFlushInterval,
+	}
+}
+
+func loadRedisConfig(s *settings) RedisConfig {
+	host := s.get("REDIS_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	return RedisConfig{
+		Host:     host,
+		Port:     s.int("REDIS_PORT", 6379),
+		Password: s.secret("REDIS_PASSWORD"),
+	}
 }
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the config file describes the shards as objects, and holds the values of the other settings
+// under the names of their environment variables, e.g.
+//
+//	shards:
+//	  - host: db-1.internal
+//	    database: urls
+//	    user: app
+//	    password: ${URLS_DB_PASSWORD}
+//	    maxOpenConns: 50
+//	settings:
+//	  REDIS_HOST: redis.internal
+//	profiles:
+//	  dev:
+//	    settings:
+//	      URL_POLICY_BLOCK_PRIVATE_NETWORKS: false
+//
+// the settings of the selected profile are merged over the top-level ones, and its shards
+// replace the top-level ones if it has any.
+type fileConfig struct {
+	Shards   []fileShard            `yaml:"shards"`
+	Settings map[string]yaml.Node   `yaml:"settings"`
+	Profiles map[string]fileProfile `yaml:"profiles"`
+}
+
+type fileProfile struct {
+	Shards   []fileShard          `yaml:"shards"`
+	Settings map[string]yaml.Node `yaml:"settings"`
+}
+
+// a shard of the config file. it is either given as a url, or by its host, port, database
+// and credentials. the optional settings override the DB_* settings for this shard.
+type fileShard struct {
+	// stable ID of the shard, its position in the list if omitted.
+	ID       *int   `yaml:"id"`
+	URL      string `yaml:"url"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Database string `yaml:"database"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// file holding the password, instead of password.
+	PasswordFile string   `yaml:"passwordFile"`
+	Replicas     []string `yaml:"replicas"`
+
+	MaxOpenConns     *int   `yaml:"maxOpenConns"`
+	MaxIdleConns     *int   `yaml:"maxIdleConns"`
+	ConnMaxLifetime  string `yaml:"connMaxLifetime"`
+	ConnMaxIdleTime  string `yaml:"connMaxIdleTime"`
+	SSLMode          string `yaml:"sslMode"`
+	SSLRootCert      string `yaml:"sslRootCert"`
+	ConnectTimeout   string `yaml:"connectTimeout"`
+	ApplicationName  string `yaml:"applicationName"`
+	StatementTimeout string `yaml:"statementTimeout"`
+}
+
+// the config file once its profile has been applied and its variables interpolated.
+type loadedFile struct {
+	Shards   []fileShard
+	Settings map[string]string
+	// invalid values of the file, reported together with the invalid settings.
+	errs []error
+}
+
+// matches ${VAR} and ${VAR:-default}.
+var interpolationPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// reads the config file at path, applying the named profile. returns nil if path is empty.
+// errors reading or parsing the file are returned, invalid values are left for validation.
+func loadFile(path string, profile string) (*loadedFile, error) {
+	path = strings.TrimSpace(path)
+	profile = strings.TrimSpace(profile)
+	if path == "" {
+		if profile != "" {
+			return nil, fmt.Errorf("CONFIG_PROFILE is set to '%s' but CONFIG_FILE isn't set", profile)
+		}
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the config file: %w", err)
+	}
+
+	var file fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	// misspelled fields would otherwise be ignored silently.
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse the config file %s: %w", path, err)
+	}
+
+	shards := file.Shards
+	nodes := file.Settings
+	if profile != "" {
+		selected, ok := file.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("the config file %s has no profile '%s', it has: %s", path, profile, strings.Join(profileNames(file.Profiles), ", "))
+		}
+
+		if len(selected.Shards) > 0 {
+			shards = selected.Shards
+		}
+		merged := make(map[string]yaml.Node, len(nodes)+len(selected.Settings))
+		for name, node := range nodes {
+			merged[name] = node
+		}
+		for name, node := range selected.Settings {
+			merged[name] = node
+		}
+		nodes = merged
+	}
+
+	loaded := &loadedFile{Settings: make(map[string]string, len(nodes))}
+	unset := make(map[string]bool)
+	interpolate := func(where string, value string) string {
+		return interpolationPattern.ReplaceAllStringFunc(value, func(match string) string {
+			groups := interpolationPattern.FindStringSubmatch(match)
+			if value, ok := os.LookupEnv(groups[1]); ok && value != "" {
+				return value
+			}
+			if strings.Contains(match, ":-") {
+				return groups[2]
+			}
+			if !unset[where+groups[1]] {
+				unset[where+groups[1]] = true
+				loaded.errs = append(loaded.errs, fmt.Errorf("%s of the config file refers to %s, which isn't set", where, groups[1]))
+			}
+			return ""
+		})
+	}
+
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		node := nodes[name]
+		value, err := settingValue(&node)
+		if err != nil {
+			loaded.errs = append(loaded.errs, fmt.Errorf("setting %s of the config file %w", name, err))
+			continue
+		}
+		loaded.Settings[name] = interpolate("setting "+name, value)
+	}
+
+	for i, shard := range shards {
+		where := fmt.Sprintf("shard %d", i+1)
+		for _, field := range []*string{&shard.URL, &shard.Host, &shard.Database, &shard.User, &shard.Password, &shard.PasswordFile, &shard.SSLRootCert, &shard.ApplicationName} {
+			*field = interpolate(where, *field)
+		}
+		for j := range shard.Replicas {
+			shard.Replicas[j] = interpolate(where, shard.Replicas[j])
+		}
+		loaded.Shards = append(loaded.Shards, shard)
+	}
+
+	return loaded, nil
+}
+
+// converts the YAML value of a setting to the string its environment variable would hold.
+// lists are joined with commas.
+func settingValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		items := make([]string, len(node.Content))
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("must be a value or a list of values")
+			}
+			items[i] = item.Value
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("must be a value or a list of values")
+	}
+}
+
+func profileNames(profiles map[string]fileProfile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func dbConfigsFromFile(s *settings, shards []fileShard) []DBConfig {
+	var dbConfigs []DBConfig
+
+	for i, shard := range shards {
+		shardID := i
+		if shard.ID != nil {
+			shardID = *shard.ID
+		}
+
+		if shard.URL != "" {
+			if shard.Host != "" || shard.Database != "" {
+				s.errorf("shard %d of the config file has a url as well as a host or database", i+1)
+				continue
+			}
+
+			dbConfig, err := dbConfigFromURL(shard.URL)
+			if err != nil {
+				s.errorf("shard %d of the config file: %w", i+1, err)
+				continue
+			}
+			dbConfig.ShardID = shardID
+			dbConfig.ReplicaDSNs = shard.Replicas
+			dbConfigs = append(dbConfigs, *dbConfig)
+			continue
+		}
+
+		if shard.Host == "" || shard.Database == "" || shard.User == "" {
+			s.errorf("shard %d of the config file needs a url, or a host, database and user", i+1)
+			continue
+		}
+		if shard.Port == 0 {
+			shard.Port = 5432
+		}
+		if shard.PasswordFile != "" {
+			if shard.Password != "" {
+				s.errorf("shard %d of the config file has a password as well as a passwordFile", i+1)
+				continue
+			}
+			shard.Password = s.readSecretFile(fmt.Sprintf("shard %d of the config file", i+1), shard.PasswordFile)
+		}
+
+		dbConfig := newDBConfig(shardID, shard.Host, shard.Port, shard.Database, shard.User, shard.Password)
+		dbConfig.ReplicaDSNs = shard.Replicas
+		dbConfigs = append(dbConfigs, dbConfig)
+	}
+
+	// a shard that couldn't be read would shift the others onto the wrong index.
+	if len(dbConfigs) != len(shards) {
+		return nil
+	}
+	return dbConfigs
+}
+
+// overrides the settings of the shard with the ones set in the file.
+func (shard *fileShard) applySettings(s *settings, dbConfig *DBConfig) {
+	duration := func(field string, value string, target *time.Duration) {
+		if value == "" {
+			return
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			s.errorf("invalid %s of shard %s in the config file: %w", field, dbConfig.DBName, err)
+			return
+		}
+		*target = parsed
+	}
+
+	if shard.MaxOpenConns != nil {
+		dbConfig.MaxOpenConns = *shard.MaxOpenConns
+	}
+	if shard.MaxIdleConns != nil {
+		dbConfig.MaxIdleConns = *shard.MaxIdleConns
+	}
+	duration("connMaxLifetime", shard.ConnMaxLifetime, &dbConfig.ConnMaxLifetime)
+	duration("connMaxIdleTime", shard.ConnMaxIdleTime, &dbConfig.ConnMaxIdleTime)
+	duration("connectTimeout", shard.ConnectTimeout, &dbConfig.ConnectTimeout)
+	duration("statementTimeout", shard.StatementTimeout, &dbConfig.StatementTimeout)
+
+	if shard.SSLMode != "" {
+		dbConfig.SSLMode = shard.SSLMode
+	}
+	if shard.SSLRootCert != "" {
+		dbConfig.SSLRootCert = shard.SSLRootCert
+	}
+	if shard.ApplicationName != "" {
+		dbConfig.ApplicationName = shard.ApplicationName
+	}
+}
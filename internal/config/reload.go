@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Reload returns the configuration to run with once reloaded was loaded while running.
+// the settings that can change while running (aliases, URL policy, rate limits, tenant cache,
+// trusted proxies and logging) are taken from reloaded, the others are kept from current.
+// the names of the kept settings that changed are returned, as they need a restart.
+//
+// changed credentials aren't reported, as they are picked up by the secret refresh.
+func Reload(current *Config, reloaded *Config) (*Config, []string) {
+	next := *current
+	next.AliasConfig = reloaded.AliasConfig
+	next.UrlPolicyConfig = reloaded.UrlPolicyConfig
+	next.RateLimitConfig = reloaded.RateLimitConfig
+	next.TenantConfig = reloaded.TenantConfig
+	next.ServerConfig = reloaded.ServerConfig
+	next.LogConfig = reloaded.LogConfig
+
+	var restartRequired []string
+	if !slices.Equal(shardTopology(current.DBConfigs), shardTopology(reloaded.DBConfigs)) {
+		restartRequired = append(restartRequired, "the shard topology")
+	} else if !reflect.DeepEqual(withoutCredentials(current.DBConfigs), withoutCredentials(reloaded.DBConfigs)) {
+		restartRequired = append(restartRequired, "the shard connection settings")
+	}
+
+	currentRedis, reloadedRedis := current.RedisConfig, reloaded.RedisConfig
+	currentRedis.Password, reloadedRedis.Password = "", ""
+
+	for _, section := range []struct {
+		name              string
+		current, reloaded interface{}
+	}{
+		{"the migration settings", current.MigrationConfig, reloaded.MigrationConfig},
+		{"the replica settings", current.ReplicaConfig, reloaded.ReplicaConfig},
+		{"the Redis settings", currentRedis, reloadedRedis},
+		{"the analytics settings", current.AnalyticsConfig, reloaded.AnalyticsConfig},
+		{"the authentication settings", current.AuthConfig, reloaded.AuthConfig},
+		{"the secret settings", current.SecretsConfig, reloaded.SecretsConfig},
+		{"the reload settings", current.ReloadConfig, reloaded.ReloadConfig},
+	} {
+		if !reflect.DeepEqual(section.current, section.reloaded) {
+			restartRequired = append(restartRequired, section.name)
+		}
+	}
+
+	return &next, restartRequired
+}
+
+// returns the database of each shard, by shard ID.
+func shardTopology(dbConfigs []DBConfig) []string {
+	databases := make([]string, len(dbConfigs))
+	for i, dbConfig := range dbConfigs {
+		databases[i] = fmt.Sprintf("%d:%s:%d/%s", dbConfig.ShardID, dbConfig.Host, dbConfig.Port, dbConfig.DBName)
+	}
+	return databases
+}
+
+// returns copies of the shards without their DSNs and passwords. the number of replicas is kept.
+func withoutCredentials(dbConfigs []DBConfig) []DBConfig {
+	stripped := make([]DBConfig, len(dbConfigs))
+	for i, dbConfig := range dbConfigs {
+		dbConfig.Password = ""
+		dbConfig.DSN = ""
+		dbConfig.ReplicaDSNs = make([]string, len(dbConfig.ReplicaDSNs))
+		stripped[i] = dbConfig
+	}
+	return stripped
+}
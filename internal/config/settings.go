@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every invalid setting found while loading the configuration.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	lines := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		lines[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("%d configuration errors:\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// every setting of the configuration, including the _FILE variants of secrets. settings of the
+// config file that aren't listed are reported as unknown, whether or not this load read them.
+var knownSettings = map[string]bool{
+	"ALIAS_CACHE_TTL":                     true,
+	"ANALYTICS_BATCH_SIZE":                true,
+	"ANALYTICS_BUFFER_SIZE":               true,
+	"ANALYTICS_COUNTER_FLUSH_INTERVAL":    true,
+	"ANALYTICS_FLUSH_INTERVAL":            true,
+	"ANALYTICS_GEOIP_FILE":                true,
+	"ANALYTICS_IP_HASH_SALT":              true,
+	"ANALYTICS_IP_HASH_SALT_FILE":         true,
+	"ANALYTICS_ROLLUP_INTERVAL":           true,
+	"AUTH_JWKS_CACHE_TTL":                 true,
+	"AUTH_JWKS_URL":                       true,
+	"AUTH_JWT_AUDIENCE":                   true,
+	"AUTH_JWT_ISSUER":                     true,
+	"AUTH_JWT_TENANT_CLAIM":               true,
+	"BULK_CREATE_MAX_ITEMS":               true,
+	"CONFIG_WATCH_INTERVAL":               true,
+	"DB_APPLICATION_NAME":                 true,
+	"DB_AUTO_MIGRATE":                     true,
+	"DB_CONNECT_TIMEOUT":                  true,
+	"DB_CONN_MAX_IDLE_TIME":               true,
+	"DB_CONN_MAX_LIFETIME":                true,
+	"DB_HOST_LIST":                        true,
+	"DB_MAX_IDLE_CONNS":                   true,
+	"DB_MAX_OPEN_CONNS":                   true,
+	"DB_MIGRATION_DIR":                    true,
+	"DB_MIGRATION_LOCK_TIMEOUT":           true,
+	"DB_NAME_LIST":                        true,
+	"DB_PASSWORD_LIST":                    true,
+	"DB_PASSWORD_LIST_FILE":               true,
+	"DB_PORT_LIST":                        true,
+	"DB_REPLICA_DSN_LIST":                 true,
+	"DB_REPLICA_DSN_LIST_FILE":            true,
+	"DB_REPLICA_HEALTH_CHECK_INTERVAL":    true,
+	"DB_REPLICA_MAX_LAG":                  true,
+	"DB_SHARD_ID_LIST":                    true,
+	"DB_SSL_MODE":                         true,
+	"DB_SSL_ROOT_CERT":                    true,
+	"DB_STATEMENT_TIMEOUT":                true,
+	"DB_URL_LIST":                         true,
+	"DB_URL_LIST_FILE":                    true,
+	"DB_USER_LIST":                        true,
+	"DEFAULT_REDIRECT_CODE":               true,
+	"LOG_LEVEL":                           true,
+	"PUBLIC_BASE_URL":                     true,
+	"RATE_LIMIT_CREATE_LIMIT":             true,
+	"RATE_LIMIT_CREATE_WINDOW":            true,
+	"RATE_LIMIT_REDIRECT_LIMIT":           true,
+	"RATE_LIMIT_REDIRECT_WINDOW":          true,
+	"REDIS_HOST":                          true,
+	"REDIS_PASSWORD":                      true,
+	"REDIS_PASSWORD_FILE":                 true,
+	"REDIS_PORT":                          true,
+	"RESERVED_ALIASES":                    true,
+	"SECRETS_REFRESH_INTERVAL":            true,
+	"TENANT_CACHE_MAX_ENTRIES":            true,
+	"TENANT_CACHE_TTL":                    true,
+	"TRUSTED_PROXIES":                     true,
+	"URL_NORMALIZE_STRIP_TRACKING_PARAMS": true,
+	"URL_NORMALIZE_TRACKING_PARAMS":       true,
+	"URL_POLICY_ALLOWED_HOSTS":            true,
+	"URL_POLICY_ALLOWED_SCHEMES":          true,
+	"URL_POLICY_BLOCKLIST_FILE":           true,
+	"URL_POLICY_BLOCK_PRIVATE_NETWORKS":   true,
+	"URL_POLICY_DENIED_HOSTS":             true,
+	"URL_POLICY_MAX_LENGTH":               true,
+}
+
+// settings reads named values from the environment, falling back to the settings of the
+// config file. invalid values are recorded instead of stopping the load, so that they
+// can all be reported at once.
+type settings struct {
+	file map[string]string
+	errs []error
+	// files secrets were read from.
+	secretFiles []string
+}
+
+func newSettings(file map[string]string) *settings {
+	return &settings{file: file}
+}
+
+// returns the trimmed value of the setting. variables set to an empty value are treated as
+// unset, so that an empty entry of a .env file doesn't hide the value of the config file.
+func (s *settings) get(name string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return strings.TrimSpace(s.file[name])
+}
+
+// reads a secret from the file named by the NAME_FILE setting, as mounted by Kubernetes,
+// or from the NAME setting if it isn't set.
+func (s *settings) secret(name string) string {
+	path := s.get(name + "_FILE")
+	if path == "" {
+		return s.get(name)
+	}
+	return s.readSecretFile(name+"_FILE", path)
+}
+
+// returns the content of the secret file without its trailing line break.
+func (s *settings) readSecretFile(setting string, path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		s.errorf("failed to read the secret file of %s: %w", setting, err)
+		return ""
+	}
+
+	s.secretFiles = append(s.secretFiles, path)
+	return strings.TrimRight(string(content), "\r\n")
+}
+
+// records an invalid setting.
+func (s *settings) errorf(format string, args ...interface{}) {
+	s.errs = append(s.errs, fmt.Errorf(format, args...))
+}
+
+// reads an integer, returning def if it is unset or invalid.
+func (s *settings) int(name string, def int) int {
+	value := s.get(name)
+	if value == "" {
+		return def
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		s.errorf("invalid value for %s: %w", name, err)
+		return def
+	}
+	return parsed
+}
+
+// reads a duration (e.g. "5s", "1m"), returning def if it is unset or invalid.
+func (s *settings) duration(name string, def time.Duration) time.Duration {
+	value := s.get(name)
+	if value == "" {
+		return def
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		s.errorf("invalid value for %s: %w", name, err)
+		return def
+	}
+	return parsed
+}
+
+// reads a boolean (e.g. "true", "0"), returning def if it is unset or invalid.
+func (s *settings) bool(name string, def bool) bool {
+	value := s.get(name)
+	if value == "" {
+		return def
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		s.errorf("invalid value for %s: %w", name, err)
+		return def
+	}
+	return parsed
+}
+
+// reads a comma-separated list, dropping empty entries.
+func (s *settings) list(name string) []string {
+	var items []string
+	for _, item := range strings.Split(s.get(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// reads a setting that is either a single value applying to every shard, or a comma-separated
+// list with one value per shard. unset values take def.
+func (s *settings) shardValues(name string, shardCount int, def string) []string {
+	values := make([]string, shardCount)
+
+	items := strings.Split(s.get(name), ",")
+	if len(items) != 1 && len(items) != shardCount {
+		s.errorf("%s must have a single value or one per shard (%d)", name, shardCount)
+		items = items[:1]
+	}
+
+	for i := range values {
+		item := items[0]
+		if len(items) > 1 {
+			item = items[i]
+		}
+		if item = strings.TrimSpace(item); item == "" {
+			item = def
+		}
+		values[i] = item
+	}
+	return values
+}
+
+func (s *settings) shardInts(name string, shardCount int, def int) []int {
+	parsed := make([]int, shardCount)
+	for i, value := range s.shardValues(name, shardCount, strconv.Itoa(def)) {
+		var err error
+		if parsed[i], err = strconv.Atoi(value); err != nil {
+			s.errorf("invalid value for %s: %w", name, err)
+			parsed[i] = def
+		}
+	}
+	return parsed
+}
+
+func (s *settings) shardDurations(name string, shardCount int, def time.Duration) []time.Duration {
+	parsed := make([]time.Duration, shardCount)
+	for i, value := range s.shardValues(name, shardCount, def.String()) {
+		var err error
+		if parsed[i], err = time.ParseDuration(value); err != nil {
+			s.errorf("invalid value for %s: %w", name, err)
+			parsed[i] = def
+		}
+	}
+	return parsed
+}
+
+// records an error for every setting of the config file that isn't known, most likely a typo.
+func (s *settings) checkUnknown() {
+	var unknown []string
+	for name := range s.file {
+		if !knownSettings[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		s.errorf("unknown setting %s in the config file", name)
+	}
+}
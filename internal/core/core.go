@@ -1,9 +1,21 @@
 package core
 
+import "fmt"
+
 // desired length of the url alias. can support 62^8 unique strings
 const ALIAS_LEN = 8;
 
-// generates the url alias from the original url using the provided strategy
-func GenerateAlias(original_url string, aliasingStrategy AliasingStrategy) (string, error) {
-	return aliasingStrategy.Alias(original_url, ALIAS_LEN), nil
+// number of aliases generated before giving up on finding one that isn't reserved.
+const MAX_ALIAS_ATTEMPTS = 5;
+
+// generates the url alias from the original url using the provided strategy.
+// reserved aliases are never returned.
+func GenerateAlias(original_url string, aliasingStrategy AliasingStrategy, reservedAliases *ReservedAliases) (string, error) {
+	for i := 0; i < MAX_ALIAS_ATTEMPTS; i++ {
+		alias := aliasingStrategy.Alias(original_url, ALIAS_LEN)
+		if !reservedAliases.IsReserved(alias) {
+			return alias, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate an alias that isn't reserved after %d attempts", MAX_ALIAS_ATTEMPTS)
 }
\ No newline at end of file
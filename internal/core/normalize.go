@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// query parameters stripped by default when tracking parameter stripping is enabled.
+// entries ending with '*' match every parameter starting with the prefix.
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "_ga", "yclid"}
+
+// UrlNormalizer canonicalizes URLs so that equivalent URLs can be detected.
+type UrlNormalizer struct {
+	// removes tracking parameters from the query.
+	StripTrackingParams bool
+	// parameters removed if StripTrackingParams is set, see DefaultTrackingParams.
+	TrackingParams []string
+}
+
+// returns the canonical form of the URL:
+//   - the scheme and host are lowercased, and default ports removed
+//   - percent-encodings of unreserved characters are decoded and the others uppercased
+//   - a trailing slash is removed from the path, and an empty path becomes "/"
+//   - query parameters are sorted by key, and tracking parameters optionally removed
+func (n *UrlNormalizer) Normalize(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	if parsed.Opaque != "" {
+		// e.g. "mailto:", which has no host or path to normalize.
+		return rawURL, nil
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := parsed.Port(); port != "" && !isDefaultPort(scheme, port) {
+		host += ":" + port
+	}
+
+	path := normalizePercentEncoding(parsed.EscapedPath())
+	if path == "" {
+		path = "/"
+	} else if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme + "://")
+	if parsed.User != nil {
+		b.WriteString(parsed.User.String() + "@")
+	}
+	b.WriteString(host)
+	b.WriteString(path)
+
+	if query := n.normalizeQuery(parsed.RawQuery); query != "" {
+		b.WriteString("?" + query)
+	}
+	if parsed.Fragment != "" {
+		b.WriteString("#" + normalizePercentEncoding(parsed.EscapedFragment()))
+	}
+	return b.String(), nil
+}
+
+func (n *UrlNormalizer) normalizeQuery(rawQuery string) string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		param = normalizePercentEncoding(param)
+		if n.StripTrackingParams && n.isTrackingParam(param) {
+			continue
+		}
+		params = append(params, param)
+	}
+
+	// parameters are sorted by key only, as the order of a repeated key's values can matter.
+	sort.SliceStable(params, func(i, j int) bool {
+		keyI, _, _ := strings.Cut(params[i], "=")
+		keyJ, _, _ := strings.Cut(params[j], "=")
+		return keyI < keyJ
+	})
+	return strings.Join(params, "&")
+}
+
+func (n *UrlNormalizer) isTrackingParam(param string) bool {
+	key, _, _ := strings.Cut(param, "=")
+	key = strings.ToLower(key)
+
+	for _, tracking := range n.TrackingParams {
+		tracking = strings.ToLower(tracking)
+		if prefix, isPrefix := strings.CutSuffix(tracking, "*"); isPrefix {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == tracking {
+			return true
+		}
+	}
+	return false
+}
+
+func isDefaultPort(scheme string, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
+
+// decodes percent-encoded unreserved characters (RFC 3986, section 6.2.2.2) and uppercases
+// the hex digits of the remaining percent-encodings.
+func normalizePercentEncoding(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			b.WriteByte(decoded)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
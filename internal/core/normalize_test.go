@@ -0,0 +1,73 @@
+package core
+
+import "testing"
+
+func TestUrlNormalizerNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		strip bool
+		want  string
+	}{
+		{"canonical", "https://example.com/a?b=1", false, "https://example.com/a?b=1"},
+		{"lowercases the scheme and host", "HTTPS://Example.COM/Path", false, "https://example.com/Path"},
+		{"removes the trailing dot of the host", "https://example.com./a", false, "https://example.com/a"},
+		{"removes default ports", "http://example.com:80/a", false, "http://example.com/a"},
+		{"removes the https default port", "https://example.com:443/a", false, "https://example.com/a"},
+		{"keeps other ports", "https://example.com:8443/a", false, "https://example.com:8443/a"},
+		{"adds a root path", "https://example.com", false, "https://example.com/"},
+		{"removes the trailing slash", "https://example.com/a/", false, "https://example.com/a"},
+		{"keeps the root path", "https://example.com/", false, "https://example.com/"},
+		{"decodes unreserved characters", "https://example.com/%7Euser/%61", false, "https://example.com/~user/a"},
+		{"uppercases other percent-encodings", "https://example.com/a%2fb?q=%c3%a9", false, "https://example.com/a%2Fb?q=%C3%A9"},
+		{"sorts query parameters by key", "https://example.com/?b=2&a=1&c=3", false, "https://example.com/?a=1&b=2&c=3"},
+		{"keeps the order of repeated keys", "https://example.com/?b=2&a=z&a=y", false, "https://example.com/?a=z&a=y&b=2"},
+		{"drops empty parameters", "https://example.com/?&a=1&&", false, "https://example.com/?a=1"},
+		{"keeps the user info", "https://user@Example.com/a", false, "https://user@example.com/a"},
+		{"keeps the fragment", "https://example.com/a#Top%7e", false, "https://example.com/a#Top~"},
+		{"brackets IPv6 hosts", "http://[::1]:8080/a", false, "http://[::1]:8080/a"},
+		{"leaves opaque URLs alone", "mailto:Someone@Example.com", false, "mailto:Someone@Example.com"},
+		{"keeps tracking parameters by default", "https://example.com/?utm_source=x&a=1", false, "https://example.com/?a=1&utm_source=x"},
+		{"strips tracking parameters", "https://example.com/?utm_source=x&UTM_Medium=y&fbclid=z&a=1", true, "https://example.com/?a=1"},
+		{"keeps parameters only prefixed like exact tracking parameters", "https://example.com/?fbclid_x=1", true, "https://example.com/?fbclid_x=1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalizer := &UrlNormalizer{StripTrackingParams: test.strip, TrackingParams: DefaultTrackingParams}
+
+			got, err := normalizer.Normalize(test.url)
+			if err != nil {
+				t.Fatalf("Normalize(%q) failed: %v", test.url, err)
+			}
+			if got != test.want {
+				t.Errorf("Normalize(%q) = %q, want %q", test.url, got, test.want)
+			}
+
+			// normalizing is idempotent, so normalized URLs can be compared with each other.
+			again, err := normalizer.Normalize(got)
+			if err != nil || again != got {
+				t.Errorf("Normalize(%q) = %q, %v, want it unchanged", got, again, err)
+			}
+		})
+	}
+}
+
+func TestUrlNormalizerNormalizeInvalid(t *testing.T) {
+	normalizer := &UrlNormalizer{}
+	if _, err := normalizer.Normalize("https://exa mple.com/%zz"); err == nil {
+		t.Errorf("Normalize of an invalid URL succeeded, want an error")
+	}
+}
+
+func TestUrlNormalizerCustomTrackingParams(t *testing.T) {
+	normalizer := &UrlNormalizer{StripTrackingParams: true, TrackingParams: []string{"ref", "src_*"}}
+
+	got, err := normalizer.Normalize("https://example.com/?ref=a&src_id=b&utm_source=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/?utm_source=c"; got != want {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import "strings"
+
+// paths served by the service itself, which therefore can't be used as aliases.
+var defaultReservedAliases = []string{
+	"api",
+	"swagger",
+	"docs",
+	"health",
+	"metrics",
+	"admin",
+	"login",
+	"logout",
+	"static",
+	"assets",
+	"favicon.ico",
+	"robots.txt",
+	"sitemap.xml",
+	".well-known",
+}
+
+// ReservedAliases is the set of aliases that would collide with routes on the root path.
+type ReservedAliases struct {
+	aliases map[string]struct{}
+}
+
+// creates the set of default reserved aliases together with the extra ones.
+func NewReservedAliases(extra []string) *ReservedAliases {
+	aliases := make(map[string]struct{}, len(defaultReservedAliases)+len(extra))
+	for _, alias := range append(defaultReservedAliases, extra...) {
+		aliases[strings.ToLower(alias)] = struct{}{}
+	}
+	return &ReservedAliases{aliases: aliases}
+}
+
+// returns true if the alias is reserved. aliases are compared case-insensitively,
+// as some clients and proxies change the case of paths.
+func (ra *ReservedAliases) IsReserved(alias string) bool {
+	_, reserved := ra.aliases[strings.ToLower(alias)]
+	return reserved
+}
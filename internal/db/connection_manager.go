@@ -1,36 +1,137 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/lib/pq"
-	"github.com/pressly/goose/v3"
+	"github.com/lib/pq"
 	"github.com/shashwatrathod/url-shortner/internal/utils"
 )
 
+// shards are held by shard ID, which is their index in the slices below.
 type ConnectionManager struct {
-	shards       []*sql.DB
-	shardsByName map[string]int
+	shards     []*sql.DB
+	shardNames []string
+
+	// connectors and pool settings of the shards, to change the DSN of a shard after credentials rotated.
+	connectors []*dsnConnector
+	pools      []PoolConfig
+
+	// read replicas of every shard, in the order of shards.
+	replicas [][]*replica
+	// per shard counter used to pick replicas round-robin.
+	nextReplica []atomic.Uint64
+
+	stopHealthChecks chan struct{}
+	healthChecks     sync.WaitGroup
+	closeOnce        sync.Once
 }
 
 type ConnectionConfig struct {
-	DSN       string
+	DSN string
+	// stable ID of the shard, from 0 to the number of shards - 1. keys are routed to shards by ID.
+	ShardID int
+	// name of the shard in logs, e.g. its database name. names don't need to be unique.
 	ShardName string
+	// optional read replicas of the shard, serving read-only queries.
+	ReplicaDSNs []string
+	// pool settings of the shard and of each of its replicas.
+	Pool PoolConfig
+}
+
+// PoolConfig holds the settings of a connection pool, see the matching setters of sql.DB.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (p PoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// opens connections with the DSN it currently holds, so that new connections of a pool
+// use rotated credentials without the pool being reopened.
+type dsnConnector struct {
+	connector atomic.Pointer[pq.Connector]
+	dsn       atomic.Pointer[string]
+}
+
+func newDSNConnector(dsn string) (*dsnConnector, error) {
+	c := &dsnConnector{}
+	if err := c.setDSN(dsn); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *dsnConnector) setDSN(dsn string) error {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return err
+	}
+	c.store(connector, dsn)
+	return nil
+}
+
+func (c *dsnConnector) store(connector *pq.Connector, dsn string) {
+	c.connector.Store(connector)
+	c.dsn.Store(&dsn)
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.connector.Load().Connect(ctx)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.connector.Load().Driver()
+}
+
+// a read replica of a shard. replicas only serve reads once a health check found them
+// reachable and caught up with the primary.
+type replica struct {
+	name      string
+	db        *sql.DB
+	connector *dsnConnector
+	healthy   atomic.Bool
 }
 
 // initializes a new ConnectionManager by opening connections configured in the provided configs.
+// the configs can be in any order, but their shard IDs must number them from 0, each ID being used once.
 func NewConnectionManager(configs []ConnectionConfig) (*ConnectionManager, error) {
 	shards := make([]*sql.DB, len(configs))
-	shardsByName := make(map[string]int)
+	shardNames := make([]string, len(configs))
+	replicas := make([][]*replica, len(configs))
+	connectors := make([]*dsnConnector, len(configs))
+	pools := make([]PoolConfig, len(configs))
 
-	for idx, config := range configs {
-		db, err := sql.Open("postgres", config.DSN)
+	for _, config := range configs {
+		idx := config.ShardID
+		if idx < 0 || idx >= len(configs) {
+			return nil, fmt.Errorf("shard %s: the shard ID must be between 0 and %d, got %d", config.ShardName, len(configs)-1, idx)
+		}
+		if shards[idx] != nil {
+			return nil, fmt.Errorf("shards %s and %s have the same shard ID %d", shardNames[idx], config.ShardName, idx)
+		}
+
+		connector, err := newDSNConnector(config.DSN)
 		if err != nil {
 			return nil, err
 		}
+		db := sql.OpenDB(connector)
+		config.Pool.apply(db)
 
 		err = db.Ping()
 		if err != nil {
@@ -38,10 +139,96 @@ func NewConnectionManager(configs []ConnectionConfig) (*ConnectionManager, error
 		}
 
 		shards[idx] = db
-		shardsByName[config.ShardName] = idx
-		log.Printf("Connected to shard: %s", config.ShardName)
+		connectors[idx] = connector
+		pools[idx] = config.Pool
+		shardNames[idx] = shardLabel(idx, config.ShardName)
+		log.Printf("Connected to shard: %s", shardNames[idx])
+
+		// replicas are optional, so an unreachable one only stays out of rotation until it is reachable.
+		for replicaIdx, dsn := range config.ReplicaDSNs {
+			replicaConnector, err := newDSNConnector(dsn)
+			if err != nil {
+				return nil, fmt.Errorf("invalid replica %d of shard %s: %w", replicaIdx+1, shardNames[idx], err)
+			}
+			replicaDB := sql.OpenDB(replicaConnector)
+			config.Pool.apply(replicaDB)
+			replicas[idx] = append(replicas[idx], &replica{
+				name:      fmt.Sprintf("%s/replica-%d", shardNames[idx], replicaIdx+1),
+				db:        replicaDB,
+				connector: replicaConnector,
+			})
+		}
 	}
-	return &ConnectionManager{shards: shards, shardsByName: shardsByName}, nil
+	return &ConnectionManager{
+		shards:           shards,
+		shardNames:       shardNames,
+		replicas:         replicas,
+		connectors:       connectors,
+		pools:            pools,
+		nextReplica:      make([]atomic.Uint64, len(configs)),
+		stopHealthChecks: make(chan struct{}),
+	}, nil
+}
+
+// returns the ID of the shard responsible for the key among shardCount shards.
+// it doesn't depend on open connections, so shards can be located without connecting to them.
+func ShardIndex(key string, shardCount int) int {
+	return int(utils.Hash(key) % uint64(shardCount))
+}
+
+// names the shard in logs and errors, e.g. "1 (urls)".
+func shardLabel(shardID int, name string) string {
+	return fmt.Sprintf("%d (%s)", shardID, name)
+}
+
+// returns the number of shards. their IDs go from 0 to the number of shards - 1.
+func (cm *ConnectionManager) ShardCount() int {
+	return len(cm.shards)
+}
+
+// returns the name of the shard with the ID, including its ID.
+func (cm *ConnectionManager) ShardName(shardID int) string {
+	return cm.shardNames[shardID]
+}
+
+// returns the name of the shard responsible for the key.
+func (cm *ConnectionManager) ShardNameByShardKey(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("The key is empty.")
+	}
+	return cm.shardNames[ShardIndex(key, len(cm.shards))], nil
+}
+
+// returns the ID of the shard referred to by its ID, or by its name if no other shard has it.
+func (cm *ConnectionManager) FindShardID(ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		if id < 0 || id >= len(cm.shards) {
+			return 0, fmt.Errorf("unknown shard %d, shard IDs go from 0 to %d", id, len(cm.shards)-1)
+		}
+		return id, nil
+	}
+
+	found := -1
+	for id, name := range cm.shardNames {
+		if name != shardLabel(id, ref) {
+			continue
+		}
+		if found >= 0 {
+			return 0, fmt.Errorf("shards %d and %d are both named '%s', use the shard ID", found, id, ref)
+		}
+		found = id
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("unknown shard '%s'", ref)
+	}
+	return found, nil
+}
+
+func (cm *ConnectionManager) shardByID(shardID int) (*sql.DB, error) {
+	if shardID < 0 || shardID >= len(cm.shards) {
+		return nil, fmt.Errorf("unknown shard %d", shardID)
+	}
+	return cm.shards[shardID], nil
 }
 
 // returns the DB Shard responsible to handle the provided key.
@@ -51,10 +238,35 @@ func (cm *ConnectionManager) GetShardByShardKey(key string) (*sql.DB, error) {
 		return nil, fmt.Errorf("The key is empty.")
 	}
 
-	hash := utils.Hash(key)
-	shardIdx := hash % uint64(len(cm.shards))
+	return cm.shards[ShardIndex(key, len(cm.shards))], nil
+}
 
-	return cm.shards[shardIdx], nil
+// returns a connection to read from the shard responsible for the provided key: a healthy
+// replica picked round-robin, or the primary if the shard has none.
+// reads may lag behind writes by up to the maximum replica lag.
+func (cm *ConnectionManager) GetReadShardByShardKey(key string) (*sql.DB, error) {
+
+	if key == "" {
+		return nil, fmt.Errorf("The key is empty.")
+	}
+
+	return cm.readShard(ShardIndex(key, len(cm.shards))), nil
+}
+
+func (cm *ConnectionManager) readShard(idx int) *sql.DB {
+	replicas := cm.replicas[idx]
+	if len(replicas) == 0 {
+		return cm.shards[idx]
+	}
+
+	start := cm.nextReplica[idx].Add(1)
+	for i := range replicas {
+		candidate := replicas[(start+uint64(i))%uint64(len(replicas))]
+		if candidate.healthy.Load() {
+			return candidate.db
+		}
+	}
+	return cm.shards[idx]
 }
 
 // iterates over all database connections and executes the provided function
@@ -72,47 +284,190 @@ func (cm *ConnectionManager) ForEachWithResult(fn func(db *sql.DB) (interface{},
 	return nil, nil
 }
 
-// applies db migrations from the DB_MIGRATION_DIR to all the db shards
-// using goose.
-// returns error if any of the migrations couldn't be applied.
-func (cm *ConnectionManager) ApplyMigrations() error {
+// like ForEachWithResult, but runs the function against a read connection of every shard
+// as returned by GetReadShardByShardKey. the function must not write.
+func (cm *ConnectionManager) ForEachReadWithResult(fn func(db *sql.DB) (interface{}, error)) (interface{}, error) {
+	for idx := range cm.shards {
+		result, err := fn(cm.readShard(idx))
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+	return nil, nil
+}
+
+// executes the provided function against every database shard.
+// Unlike ForEachWithResult, a failure on one shard doesn't stop the others;
+// all errors are joined and returned together.
+func (cm *ConnectionManager) ForEach(fn func(db *sql.DB) error) error {
+	var errs []error
+	for _, db := range cm.shards {
+		if err := fn(db); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// changes the DSNs the shard and its replicas connect with, e.g. once their credentials were
+// rotated. the new DSN of the shard is tried on a connection of its own before the pool uses it,
+// and the idle connections opened with the old one are closed. connections in use at that time
+// are kept once returned, until they are closed by the pool's ConnMaxLifetime or ConnMaxIdleTime;
+// they stay valid, as Postgres only checks credentials when connecting.
+// returns false if the DSNs didn't change.
+func (cm *ConnectionManager) UpdateDSNs(shardID int, dsn string, replicaDSNs []string) (bool, error) {
+	if _, err := cm.shardByID(shardID); err != nil {
+		return false, err
+	}
+	idx, shardName := shardID, cm.shardNames[shardID]
+	if len(replicaDSNs) != len(cm.replicas[idx]) {
+		return false, fmt.Errorf("the replicas of shard %s changed, which needs a restart", shardName)
+	}
+
+	changed := false
+	connector := cm.connectors[idx]
+	if *connector.dsn.Load() != dsn {
+		candidate, err := pq.NewConnector(dsn)
+		if err != nil {
+			return false, fmt.Errorf("invalid DSN of shard %s: %w", shardName, err)
+		}
+
+		// the old DSN is kept if the new one doesn't work yet, e.g. while the password is being changed.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		conn, err := candidate.Connect(ctx)
+		cancel()
+		if err != nil {
+			return false, fmt.Errorf("failed to connect to shard %s with the new DSN: %w", shardName, err)
+		}
+		conn.Close()
+
+		connector.store(candidate, dsn)
 
-	migrationsDir := os.Getenv("DB_MIGRATION_DIR")
-	if migrationsDir == "" {
-		return fmt.Errorf("DB_MIGRATION_DIR environment variable not set")
+		cm.closeIdleConns(cm.shards[idx], cm.pools[idx])
+		changed = true
 	}
 
-	dbDriver := os.Getenv("DB_DRIVER")
-	if dbDriver == "" {
-		return fmt.Errorf("DB_DRIVER environment variable not set")
+	// replicas are health checked, so a replica that can't be reached with its new DSN is taken out of rotation.
+	for i, r := range cm.replicas[idx] {
+		if *r.connector.dsn.Load() == replicaDSNs[i] {
+			continue
+		}
+		if err := r.connector.setDSN(replicaDSNs[i]); err != nil {
+			return changed, fmt.Errorf("invalid DSN of replica %s: %w", r.name, err)
+		}
+		cm.closeIdleConns(r.db, cm.pools[idx])
+		changed = true
 	}
 
-	log.Printf("applying DB Migrations on all shards using Goose..")
+	return changed, nil
+}
+
+func (cm *ConnectionManager) closeIdleConns(db *sql.DB, pool PoolConfig) {
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+}
 
-	// configure Goose.
-	if err := goose.SetDialect(os.Getenv("DB_DRIVER")); err != nil {
-		return fmt.Errorf("failed to set goose dialect: %w", err)
+// checks the replicas now and then once every interval until CloseAll is called. replicas
+// are taken out of rotation while they are unreachable or lag more than maxLag behind their
+// primary. until the checks are started, reads are served by the primaries.
+func (cm *ConnectionManager) StartReplicaHealthChecks(interval time.Duration, maxLag time.Duration) {
+	var all []*replica
+	for _, replicas := range cm.replicas {
+		all = append(all, replicas...)
 	}
-	log.Printf("successfully set goose dialect.")
+	if len(all) == 0 {
+		return
+	}
+
+	check := func() {
+		for _, r := range all {
+			r.check(interval, maxLag)
+		}
+	}
+	check()
+
+	cm.healthChecks.Add(1)
+	go func() {
+		defer cm.healthChecks.Done()
 
-	for shardName, dbIdx := range cm.shardsByName {
-		db := cm.shards[dbIdx]
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
 
-		if err := goose.Up(db, migrationsDir); err != nil {
-			return fmt.Errorf("failed to apply migrations to shard %s: %w", shardName, err)
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-cm.stopHealthChecks:
+				return
+			}
+		}
+	}()
+}
+
+// the lag is the age of the last replayed transaction, or 0 if the replica is streaming from its
+// primary and has replayed everything it received, as an idle primary doesn't produce new
+// transactions. a replica that lost its primary falls behind however little it has to replay.
+// the status of the WAL receiver is only visible to roles with pg_read_all_stats (e.g. through
+// pg_monitor); without it, the lag is the age of the last replayed transaction. the lag is
+// unknown (NULL) if the replica is behind without having replayed any transaction yet.
+const replicaLagQuery = `SELECT CASE
+        WHEN NOT pg_is_in_recovery() THEN 0
+        WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn()
+            AND EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming') THEN 0
+        ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+    END`
+
+func (r *replica) check(timeout time.Duration, maxLag time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	healthy := true
+	var lagSeconds sql.NullFloat64
+	if err := r.db.QueryRowContext(ctx, replicaLagQuery).Scan(&lagSeconds); err != nil {
+		healthy = false
+		if r.healthy.Load() {
+			log.Printf("replica %s is unreachable, reading from the primary: %v", r.name, err)
+		}
+	} else if !lagSeconds.Valid {
+		healthy = false
+		if r.healthy.Load() {
+			log.Printf("replica %s is behind without having replayed any transaction, reading from the primary", r.name)
+		}
+	} else if lagSeconds.Float64 > maxLag.Seconds() {
+		healthy = false
+		if r.healthy.Load() {
+			lag := time.Duration(lagSeconds.Float64 * float64(time.Second))
+			log.Printf("replica %s lags %s behind, reading from the primary", r.name, lag)
 		}
-		log.Printf("successfully applied migrations to shard: %s", shardName)
 	}
 
-	log.Println("all shards processed for migrations.")
-	return nil
+	if r.healthy.Swap(healthy) != healthy && healthy {
+		log.Printf("replica %s is healthy, serving reads", r.name)
+	}
 }
 
-// closes all connections held by this connection manager.
+// closes all connections held by this connection manager. later calls do nothing.
 func (cm *ConnectionManager) CloseAll() {
+	cm.closeOnce.Do(cm.closeAll)
+}
+
+func (cm *ConnectionManager) closeAll() {
+	close(cm.stopHealthChecks)
+	cm.healthChecks.Wait()
+
 	for _, db := range cm.shards {
 		if err := db.Close(); err != nil {
 			log.Printf("error closing database connection: %v", err)
 		}
 	}
+	for _, replicas := range cm.replicas {
+		for _, r := range replicas {
+			if err := r.db.Close(); err != nil {
+				log.Printf("error closing database connection: %v", err)
+			}
+		}
+	}
 }
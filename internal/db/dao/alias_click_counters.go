@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db"
+)
+
+// click counts of an alias within a single hour, as tracked by the real-time counters.
+type HourlyClickCounter struct {
+	Alias          string
+	Bucket         time.Time
+	Clicks         int64
+	UniqueVisitors int64
+}
+
+// defines the interface for persisted real-time click counter operations.
+type AliasClickCounterDao interface {
+	// stores the given counters on the shards owning their aliases.
+	// existing counters are only ever increased, so replaying stale values is harmless.
+	UpsertCounters(ctx context.Context, counters []HourlyClickCounter) error
+
+	// returns the hourly counters of the alias within [from, to), ordered by bucket.
+	GetCounters(ctx context.Context, alias string, from time.Time, to time.Time) ([]HourlyClickCounter, error)
+}
+
+// aliasClickCounterDaoImpl is the concrete implementation of AliasClickCounterDao.
+type aliasClickCounterDaoImpl struct {
+	connManager *db.ConnectionManager
+}
+
+// creates a new instance of aliasClickCounterDaoImpl.
+func NewAliasClickCounterDao(cm *db.ConnectionManager) AliasClickCounterDao {
+	return &aliasClickCounterDaoImpl{
+		connManager: cm,
+	}
+}
+
+// number of columns written per counter row.
+const clickCounterColumns = 4
+
+func (d *aliasClickCounterDaoImpl) UpsertCounters(ctx context.Context, counters []HourlyClickCounter) error {
+	if d.connManager == nil {
+		return fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	countersByShard := make(map[*sql.DB][]HourlyClickCounter)
+	for _, counter := range counters {
+		shardDB, err := d.connManager.GetShardByShardKey(counter.Alias) // Use alias as sharding key
+		if err != nil {
+			return fmt.Errorf("failed to get shard for key %s: %w", counter.Alias, err)
+		}
+		countersByShard[shardDB] = append(countersByShard[shardDB], counter)
+	}
+
+	for shardDB, shardCounters := range countersByShard {
+		placeholders := make([]string, len(shardCounters))
+		args := make([]interface{}, 0, len(shardCounters)*clickCounterColumns)
+
+		for i, counter := range shardCounters {
+			base := i * clickCounterColumns
+			placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", base+1, base+2, base+3, base+4)
+			args = append(args, counter.Alias, counter.Bucket, counter.Clicks, counter.UniqueVisitors)
+		}
+
+		query := `INSERT INTO alias_click_counters (alias, bucket, clicks, unique_visitors) VALUES ` +
+			strings.Join(placeholders, ", ") + `
+                  ON CONFLICT (alias, bucket) DO UPDATE SET
+                  clicks = GREATEST(alias_click_counters.clicks, EXCLUDED.clicks),
+                  unique_visitors = GREATEST(alias_click_counters.unique_visitors, EXCLUDED.unique_visitors),
+                  updated_at = CURRENT_TIMESTAMP`
+
+		if _, err := shardDB.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to upsert click counters: %w", err)
+		}
+	}
+	return nil
+}
+
+func (d *aliasClickCounterDaoImpl) GetCounters(ctx context.Context, alias string, from time.Time, to time.Time) ([]HourlyClickCounter, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+	shardDB, err := d.connManager.GetShardByShardKey(alias) // Use alias as sharding key
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", alias, err)
+	}
+
+	query := `SELECT alias, bucket, clicks, unique_visitors FROM alias_click_counters
+              WHERE alias = $1 AND bucket >= $2 AND bucket < $3
+              ORDER BY bucket`
+
+	rows, err := shardDB.QueryContext(ctx, query, alias, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query click counters: %w", err)
+	}
+	defer rows.Close()
+
+	var counters []HourlyClickCounter
+	for rows.Next() {
+		var counter HourlyClickCounter
+		if err := rows.Scan(&counter.Alias, &counter.Bucket, &counter.Clicks, &counter.UniqueVisitors); err != nil {
+			return nil, fmt.Errorf("failed to scan click counter: %w", err)
+		}
+		counters = append(counters, counter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read click counters: %w", err)
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db"
+)
+
+// defines the structure for a single recorded click on an alias.
+type AliasClick struct {
+	Alias     string    `json:"alias"` // Tenant qualified alias key, see AliasKey
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer"`
+	UserAgent string    `json:"user_agent"`
+	Country   string    `json:"country"`
+	IPHash    string    `json:"ip_hash"`
+}
+
+// defines the interface for click data access operations.
+type AliasClickDao interface {
+	// inserts the given clicks. every click is stored on the same shard as its alias.
+	InsertClicks(ctx context.Context, clicks []AliasClick) error
+}
+
+// aliasClickDaoImpl is the concrete implementation of AliasClickDao.
+type aliasClickDaoImpl struct {
+	connManager *db.ConnectionManager
+}
+
+// creates a new instance of aliasClickDaoImpl.
+func NewAliasClickDao(cm *db.ConnectionManager) AliasClickDao {
+	return &aliasClickDaoImpl{
+		connManager: cm,
+	}
+}
+
+// groups the clicks by the shard owning their alias and writes each group
+// using multi-row INSERTs. a failing shard doesn't keep the clicks of the other
+// shards from being written, and the failures of every shard are returned together.
+func (d *aliasClickDaoImpl) InsertClicks(ctx context.Context, clicks []AliasClick) error {
+	if d.connManager == nil {
+		return fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	var errs []error
+	clicksByShard := make(map[*sql.DB][]AliasClick)
+	for _, click := range clicks {
+		shardDB, err := d.connManager.GetShardByShardKey(click.Alias) // Use alias as sharding key
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get shard for key %s: %w", click.Alias, err))
+			continue
+		}
+		clicksByShard[shardDB] = append(clicksByShard[shardDB], click)
+	}
+
+	for shardDB, shardClicks := range clicksByShard {
+		if err := insertClickBatch(ctx, shardDB, shardClicks); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// number of columns written per click row.
+const aliasClickColumns = 6
+
+// maximum number of clicks per INSERT, as Postgres accepts at most 65535 bind parameters.
+const maxClicksPerInsert = 65535 / aliasClickColumns
+
+// inserts all the given clicks into the provided shard, in as few statements as the
+// bind parameter limit allows.
+func insertClickBatch(ctx context.Context, shardDB *sql.DB, clicks []AliasClick) error {
+	for len(clicks) > maxClicksPerInsert {
+		if err := insertClicks(ctx, shardDB, clicks[:maxClicksPerInsert]); err != nil {
+			return err
+		}
+		clicks = clicks[maxClicksPerInsert:]
+	}
+	return insertClicks(ctx, shardDB, clicks)
+}
+
+// inserts the given clicks into the provided shard with one statement.
+func insertClicks(ctx context.Context, shardDB *sql.DB, clicks []AliasClick) error {
+	placeholders := make([]string, len(clicks))
+	args := make([]interface{}, 0, len(clicks)*aliasClickColumns)
+
+	for i, click := range clicks {
+		base := i * aliasClickColumns
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6)
+		args = append(args,
+			click.Alias,
+			click.ClickedAt,
+			nullableString(click.Referrer),
+			nullableString(click.UserAgent),
+			nullableString(click.Country),
+			nullableString(click.IPHash),
+		)
+	}
+
+	query := `INSERT INTO alias_clicks (alias, clicked_at, referrer, user_agent, country, ip_hash) VALUES ` +
+		strings.Join(placeholders, ", ")
+
+	if _, err := shardDB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert alias clicks: %w", err)
+	}
+	return nil
+}
+
+// maps empty strings to SQL NULL.
+func nullableString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
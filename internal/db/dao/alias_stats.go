@@ -0,0 +1,319 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db"
+)
+
+// granularities supported by the click rollups.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// dimensions supported by the click dimension rollups.
+const (
+	DimensionReferrer  = "referrer"
+	DimensionUserAgent = "user_agent"
+	DimensionCountry   = "country"
+)
+
+// clicks recorded shortly before the watermark may still be buffered by other
+// instances, so every refresh re-aggregates starting this far before it.
+const rollupLateArrivalWindow = time.Hour
+
+// aggregated click totals of an alias over a time range.
+type ClickSummary struct {
+	TotalClicks    int64
+	UniqueVisitors int64
+	// clicks recorded after this point in time are not reflected in the rollups yet.
+	RolledUpUntil time.Time
+}
+
+// click totals of an alias within a single hour or day.
+type ClickBucket struct {
+	Bucket         time.Time
+	Clicks         int64
+	UniqueVisitors int64
+}
+
+// number of clicks for a single value of a dimension (e.g. one referrer).
+type DimensionCount struct {
+	Value  string
+	Clicks int64
+}
+
+// defines the interface for click statistics data access operations.
+// aliases are identified by their tenant qualified key, see AliasKey.
+type AliasStatsDao interface {
+	// returns the total clicks and unique visitors of the alias within [from, to).
+	GetClickSummary(ctx context.Context, alias string, from time.Time, to time.Time) (*ClickSummary, error)
+
+	// returns the per-bucket clicks of the alias within [from, to), ordered by bucket.
+	// buckets without clicks are omitted.
+	GetClickTimeSeries(ctx context.Context, alias string, granularity string, from time.Time, to time.Time) ([]ClickBucket, error)
+
+	// returns the most frequent values of the dimension within the days overlapping [from, to).
+	GetTopValues(ctx context.Context, alias string, dimension string, from time.Time, to time.Time, limit int) ([]DimensionCount, error)
+
+	// re-aggregates recent raw clicks into the rollup tables on every shard.
+	RefreshRollups(ctx context.Context) error
+}
+
+// aliasStatsDaoImpl is the concrete implementation of AliasStatsDao.
+type aliasStatsDaoImpl struct {
+	connManager *db.ConnectionManager
+}
+
+// creates a new instance of aliasStatsDaoImpl.
+func NewAliasStatsDao(cm *db.ConnectionManager) AliasStatsDao {
+	return &aliasStatsDaoImpl{
+		connManager: cm,
+	}
+}
+
+// returns the shard owning the alias.
+func (d *aliasStatsDaoImpl) shardFor(alias string) (*sql.DB, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+	shardDB, err := d.connManager.GetShardByShardKey(alias) // Use alias as sharding key
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", alias, err)
+	}
+	return shardDB, nil
+}
+
+// total clicks are summed from the hourly rollups lying within the range, and unique visitors
+// counted from the daily visitor sets. the partial hours and days at either end of the range
+// are read from the raw clicks, so clicks outside of it aren't counted.
+func (d *aliasStatsDaoImpl) GetClickSummary(ctx context.Context, alias string, from time.Time, to time.Time) (*ClickSummary, error) {
+	shardDB, err := d.shardFor(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary ClickSummary
+
+	query := `SELECT rolled_up_until FROM alias_click_rollup_state WHERE id = 1`
+	if err = shardDB.QueryRowContext(ctx, query).Scan(&summary.RolledUpUntil); err != nil {
+		return nil, fmt.Errorf("failed to read rollup watermark: %w", err)
+	}
+	// raw clicks after the watermark aren't reflected in the rollups either.
+	rawTo := minTime(to, summary.RolledUpUntil)
+
+	firstHour, lastHour := wholeBuckets(from, to, time.Hour)
+	query = `SELECT COALESCE(SUM(clicks), 0) FROM alias_click_rollups
+              WHERE alias = $1 AND granularity = $2 AND bucket >= $3 AND bucket < $4`
+	err = shardDB.QueryRowContext(ctx, query, alias, GranularityHour, firstHour, lastHour).Scan(&summary.TotalClicks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum click rollups: %w", err)
+	}
+
+	var edgeClicks int64
+	query = `SELECT COUNT(*) FROM alias_clicks
+              WHERE alias = $1 AND ((clicked_at >= $2 AND clicked_at < $3) OR (clicked_at >= $4 AND clicked_at < $5))`
+	err = shardDB.QueryRowContext(ctx, query, alias, from, minTime(firstHour, rawTo), maxTime(lastHour, from), rawTo).Scan(&edgeClicks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+	summary.TotalClicks += edgeClicks
+
+	firstDay, lastDay := wholeBuckets(from, to, 24*time.Hour)
+	query = `SELECT COUNT(DISTINCT ip_hash) FROM (
+                  SELECT ip_hash FROM alias_click_visitors WHERE alias = $1 AND day >= $2 AND day < $3
+                  UNION ALL
+                  SELECT ip_hash FROM alias_clicks
+                  WHERE alias = $1 AND ((clicked_at >= $4 AND clicked_at < $5) OR (clicked_at >= $6 AND clicked_at < $7))
+              ) AS visitors`
+	err = shardDB.QueryRowContext(ctx, query, alias, firstDay, lastDay,
+		from, minTime(firstDay, rawTo), maxTime(lastDay, from), rawTo).Scan(&summary.UniqueVisitors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unique visitors: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// returns the start of the first and the end of the last bucket of the given size lying
+// entirely within [from, to). both are to if no bucket does.
+func wholeBuckets(from time.Time, to time.Time, size time.Duration) (time.Time, time.Time) {
+	first := from.Truncate(size)
+	if first.Before(from) {
+		first = first.Add(size)
+	}
+	last := to.Truncate(size)
+	if !first.Before(last) {
+		return to, to
+	}
+	return first, last
+}
+
+func (d *aliasStatsDaoImpl) GetClickTimeSeries(ctx context.Context, alias string, granularity string, from time.Time, to time.Time) ([]ClickBucket, error) {
+	shardDB, err := d.shardFor(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT bucket, clicks, unique_visitors FROM alias_click_rollups
+              WHERE alias = $1 AND granularity = $2 AND bucket >= $3 AND bucket < $4
+              ORDER BY bucket`
+
+	rows, err := shardDB.QueryContext(ctx, query, alias, granularity, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query click time series: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []ClickBucket
+	for rows.Next() {
+		var bucket ClickBucket
+		if err := rows.Scan(&bucket.Bucket, &bucket.Clicks, &bucket.UniqueVisitors); err != nil {
+			return nil, fmt.Errorf("failed to scan click bucket: %w", err)
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read click time series: %w", err)
+	}
+
+	return buckets, nil
+}
+
+func (d *aliasStatsDaoImpl) GetTopValues(ctx context.Context, alias string, dimension string, from time.Time, to time.Time, limit int) ([]DimensionCount, error) {
+	shardDB, err := d.shardFor(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT value, SUM(clicks) AS total FROM alias_click_dimension_rollups
+              WHERE alias = $1 AND dimension = $2 AND day >= $3 AND day < $4
+              GROUP BY value
+              ORDER BY total DESC, value
+              LIMIT $5`
+
+	rows, err := shardDB.QueryContext(ctx, query, alias, dimension, from.Truncate(24*time.Hour), to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top %s values: %w", dimension, err)
+	}
+	defer rows.Close()
+
+	var counts []DimensionCount
+	for rows.Next() {
+		var count DimensionCount
+		if err := rows.Scan(&count.Value, &count.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan %s count: %w", dimension, err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top %s values: %w", dimension, err)
+	}
+
+	return counts, nil
+}
+
+func (d *aliasStatsDaoImpl) RefreshRollups(ctx context.Context) error {
+	if d.connManager == nil {
+		return fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	return d.connManager.ForEach(func(shardDB *sql.DB) error {
+		return refreshShardRollups(ctx, shardDB)
+	})
+}
+
+// statements re-aggregating every bucket starting at $1 into the rollup tables.
+var rollupQueries = []string{
+	`INSERT INTO alias_click_rollups (alias, granularity, bucket, clicks, unique_visitors)
+     SELECT alias, 'hour', date_trunc('hour', clicked_at), COUNT(*), COUNT(DISTINCT ip_hash)
+     FROM alias_clicks WHERE clicked_at >= $1
+     GROUP BY alias, date_trunc('hour', clicked_at)
+     ON CONFLICT (alias, granularity, bucket)
+     DO UPDATE SET clicks = EXCLUDED.clicks, unique_visitors = EXCLUDED.unique_visitors`,
+
+	`INSERT INTO alias_click_rollups (alias, granularity, bucket, clicks, unique_visitors)
+     SELECT alias, 'day', date_trunc('day', clicked_at), COUNT(*), COUNT(DISTINCT ip_hash)
+     FROM alias_clicks WHERE clicked_at >= $1
+     GROUP BY alias, date_trunc('day', clicked_at)
+     ON CONFLICT (alias, granularity, bucket)
+     DO UPDATE SET clicks = EXCLUDED.clicks, unique_visitors = EXCLUDED.unique_visitors`,
+
+	`INSERT INTO alias_click_visitors (alias, day, ip_hash)
+     SELECT DISTINCT alias, date_trunc('day', clicked_at), ip_hash
+     FROM alias_clicks WHERE clicked_at >= $1 AND ip_hash IS NOT NULL
+     ON CONFLICT (alias, day, ip_hash) DO NOTHING`,
+
+	`INSERT INTO alias_click_dimension_rollups (alias, day, dimension, value, clicks)
+     SELECT alias, date_trunc('day', clicked_at), 'referrer', COALESCE(referrer, '(direct)'), COUNT(*)
+     FROM alias_clicks WHERE clicked_at >= $1
+     GROUP BY alias, date_trunc('day', clicked_at), COALESCE(referrer, '(direct)')
+     ON CONFLICT (alias, day, dimension, value) DO UPDATE SET clicks = EXCLUDED.clicks`,
+
+	`INSERT INTO alias_click_dimension_rollups (alias, day, dimension, value, clicks)
+     SELECT alias, date_trunc('day', clicked_at), 'user_agent', COALESCE(user_agent, '(unknown)'), COUNT(*)
+     FROM alias_clicks WHERE clicked_at >= $1
+     GROUP BY alias, date_trunc('day', clicked_at), COALESCE(user_agent, '(unknown)')
+     ON CONFLICT (alias, day, dimension, value) DO UPDATE SET clicks = EXCLUDED.clicks`,
+
+	`INSERT INTO alias_click_dimension_rollups (alias, day, dimension, value, clicks)
+     SELECT alias, date_trunc('day', clicked_at), 'country', COALESCE(country, '(unknown)'), COUNT(*)
+     FROM alias_clicks WHERE clicked_at >= $1
+     GROUP BY alias, date_trunc('day', clicked_at), COALESCE(country, '(unknown)')
+     ON CONFLICT (alias, day, dimension, value) DO UPDATE SET clicks = EXCLUDED.clicks`,
+}
+
+// re-aggregates all days touched since the shard's watermark and advances it.
+// whole days are recomputed so that hourly, daily and dimension rollups stay exact.
+func refreshShardRollups(ctx context.Context, shardDB *sql.DB) error {
+	tx, err := shardDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin rollup transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var watermark time.Time
+	query := `SELECT rolled_up_until FROM alias_click_rollup_state WHERE id = 1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query).Scan(&watermark); err != nil {
+		return fmt.Errorf("failed to read rollup watermark: %w", err)
+	}
+
+	since := watermark.Add(-rollupLateArrivalWindow).Truncate(24 * time.Hour)
+
+	var newWatermark time.Time
+	query = `SELECT COALESCE(MAX(clicked_at), $1) FROM alias_clicks WHERE clicked_at >= $2`
+	if err := tx.QueryRowContext(ctx, query, watermark, since).Scan(&newWatermark); err != nil {
+		return fmt.Errorf("failed to read latest click: %w", err)
+	}
+
+	for _, rollupQuery := range rollupQueries {
+		if _, err := tx.ExecContext(ctx, rollupQuery, since); err != nil {
+			return fmt.Errorf("failed to refresh click rollups: %w", err)
+		}
+	}
+
+	query = `UPDATE alias_click_rollup_state SET rolled_up_until = $1 WHERE id = 1`
+	if _, err := tx.ExecContext(ctx, query, newWatermark); err != nil {
+		return fmt.Errorf("failed to advance rollup watermark: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWholeBuckets(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name      string
+		from, to  string
+		wantFirst string
+		wantLast  string
+	}{
+		{"aligned", "2025-06-01T10:00:00Z", "2025-06-01T13:00:00Z", "2025-06-01T10:00:00Z", "2025-06-01T13:00:00Z"},
+		{"partial hours at both ends", "2025-06-01T10:15:00Z", "2025-06-01T13:45:00Z", "2025-06-01T11:00:00Z", "2025-06-01T13:00:00Z"},
+		{"within one hour", "2025-06-01T10:15:00Z", "2025-06-01T10:45:00Z", "2025-06-01T10:45:00Z", "2025-06-01T10:45:00Z"},
+		{"no whole hour across a boundary", "2025-06-01T10:15:00Z", "2025-06-01T11:45:00Z", "2025-06-01T11:45:00Z", "2025-06-01T11:45:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, last := wholeBuckets(at(test.from), at(test.to), time.Hour)
+			if !first.Equal(at(test.wantFirst)) || !last.Equal(at(test.wantLast)) {
+				t.Errorf("wholeBuckets(%s, %s) = %s, %s, want %s, %s", test.from, test.to, first, last, test.wantFirst, test.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shashwatrathod/url-shortner/internal/db"
+)
+
+// defines the structure for an ApiKey record.
+type ApiKey struct {
+	ID        string     `json:"id"`
+	KeyHash   string     `json:"-"`
+	Name      string     `json:"name"`
+	TenantID  string     `json:"tenant_id"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// defines the interface for API key data access operations.
+type ApiKeyDao interface {
+	// stores a new API key, only valid on the domain of the tenant.
+	CreateApiKey(ctx context.Context, id string, keyHash string, name string, scopes []string, tenantID string) (*ApiKey, error)
+
+	// retrieves an API key by its id, including revoked keys.
+	FindApiKeyByID(ctx context.Context, id string) (*ApiKey, error)
+
+	// marks the API key as revoked. returns false if no active key with the id exists.
+	RevokeApiKey(ctx context.Context, id string) (bool, error)
+}
+
+// apiKeyDaoImpl is the concrete implementation of ApiKeyDao.
+type apiKeyDaoImpl struct {
+	connManager *db.ConnectionManager
+}
+
+// creates a new instance of apiKeyDaoImpl.
+func NewApiKeyDao(cm *db.ConnectionManager) ApiKeyDao {
+	return &apiKeyDaoImpl{
+		connManager: cm,
+	}
+}
+
+// returns the shard owning the API key.
+func (d *apiKeyDaoImpl) shardFor(id string) (*sql.DB, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+	shardDB, err := d.connManager.GetShardByShardKey(id) // Use key id as sharding key
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", id, err)
+	}
+	return shardDB, nil
+}
+
+func (d *apiKeyDaoImpl) CreateApiKey(ctx context.Context, id string, keyHash string, name string, scopes []string, tenantID string) (*ApiKey, error) {
+	shardDB, err := d.shardFor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO api_keys (id, key_hash, name, scopes, tenant_id) VALUES ($1, $2, $3, $4, $5)
+               RETURNING id, key_hash, name, scopes, tenant_id, created_at, revoked_at`
+
+	createdKey, err := scanApiKey(shardDB.QueryRowContext(ctx, query, id, keyHash, name, pq.Array(scopes), tenantOrDefault(tenantID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return createdKey, nil
+}
+
+func (d *apiKeyDaoImpl) FindApiKeyByID(ctx context.Context, id string) (*ApiKey, error) {
+	shardDB, err := d.shardFor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, key_hash, name, scopes, tenant_id, created_at, revoked_at FROM api_keys WHERE id = $1`
+
+	fetchedKey, err := scanApiKey(shardDB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return fetchedKey, nil
+}
+
+func (d *apiKeyDaoImpl) RevokeApiKey(ctx context.Context, id string) (bool, error) {
+	shardDB, err := d.shardFor(id)
+	if err != nil {
+		return false, err
+	}
+
+	query := `UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`
+
+	res, err := shardDB.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func scanApiKey(row *sql.Row) (*ApiKey, error) {
+	var key ApiKey
+	var revokedAt sql.NullTime
+	err := row.Scan(
+		&key.ID,
+		&key.KeyHash,
+		&key.Name,
+		pq.Array(&key.Scopes),
+		&key.TenantID,
+		&key.CreatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
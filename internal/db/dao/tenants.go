@@ -0,0 +1,151 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db"
+)
+
+// tenant that requests are served for when their host doesn't belong to any tenant.
+// aliases created before tenants were introduced belong to it.
+const DefaultTenantID = "default"
+
+// defines the structure for a Tenant record.
+type Tenant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Domain    string    `json:"domain"` // Short domain of the tenant, without scheme or port
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// returns the key identifying the tenant's alias across shards, caches and analytics.
+// aliases of the default tenant are keyed by the bare alias, so that data from before
+// tenants were introduced stays on the same shard and under the same keys.
+func AliasKey(tenantID string, alias string) string {
+	if tenantID == "" || tenantID == DefaultTenantID {
+		return alias
+	}
+	return tenantID + "/" + alias
+}
+
+// defines the interface for tenant data access operations.
+type TenantDao interface {
+	// stores a new tenant.
+	CreateTenant(ctx context.Context, tenant *Tenant) (*Tenant, error)
+
+	// retrieves the tenant serving the domain. returns nil if there is none.
+	FindByDomain(ctx context.Context, domain string) (*Tenant, error)
+
+	// retrieves the domains of all tenants, from every shard.
+	ListDomains(ctx context.Context) ([]string, error)
+}
+
+// tenantDaoImpl is the concrete implementation of TenantDao.
+type tenantDaoImpl struct {
+	connManager *db.ConnectionManager
+}
+
+// creates a new instance of tenantDaoImpl.
+func NewTenantDao(cm *db.ConnectionManager) TenantDao {
+	return &tenantDaoImpl{
+		connManager: cm,
+	}
+}
+
+// returns the shard owning the tenant of the domain.
+func (d *tenantDaoImpl) shardFor(domain string) (*sql.DB, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+	shardDB, err := d.connManager.GetShardByShardKey(domain) // Use domain as sharding key
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", domain, err)
+	}
+	return shardDB, nil
+}
+
+func (d *tenantDaoImpl) CreateTenant(ctx context.Context, tenant *Tenant) (*Tenant, error) {
+	domain := strings.ToLower(tenant.Domain)
+
+	shardDB, err := d.shardFor(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO tenants (id, name, domain) VALUES ($1, $2, $3)
+               RETURNING id, name, domain, created_at, updated_at`
+
+	createdTenant, err := scanTenant(shardDB.QueryRowContext(ctx, query, tenant.ID, tenant.Name, domain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return createdTenant, nil
+}
+
+func (d *tenantDaoImpl) FindByDomain(ctx context.Context, domain string) (*Tenant, error) {
+	domain = strings.ToLower(domain)
+
+	shardDB, err := d.shardFor(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, name, domain, created_at, updated_at FROM tenants WHERE domain = $1`
+
+	fetchedTenant, err := scanTenant(shardDB.QueryRowContext(ctx, query, domain))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return fetchedTenant, nil
+}
+
+func (d *tenantDaoImpl) ListDomains(ctx context.Context) ([]string, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	var domains []string
+	err := d.connManager.ForEach(func(shardDB *sql.DB) error {
+		rows, err := shardDB.QueryContext(ctx, `SELECT domain FROM tenants`)
+		if err != nil {
+			return fmt.Errorf("failed to query shard: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var domain string
+			if err := rows.Scan(&domain); err != nil {
+				return fmt.Errorf("failed to scan tenant domain: %w", err)
+			}
+			domains = append(domains, domain)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant domains: %w", err)
+	}
+	return domains, nil
+}
+
+func scanTenant(row *sql.Row) (*Tenant, error) {
+	var tenant Tenant
+	err := row.Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.Domain,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
@@ -3,32 +3,136 @@ package dao
 import (
 	"context" // Added for context propagation
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/shashwatrathod/url-shortner/internal/db"
 )
 
 // defines the structure for a UrlAlias record.
 type UrlAlias struct {
-	Alias       string    `json:"short_url"`
-	OriginalURL string    `json:"original_url"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	TenantID      string     `json:"tenant_id"`
+	Alias         string     `json:"short_url"`
+	OriginalURL   string     `json:"original_url"`
+	NormalizedURL string     `json:"normalized_url"` // Canonical form of OriginalURL, used to find equivalent aliases
+	RedirectCode  int        `json:"redirect_code"`  // HTTP status used to redirect to OriginalURL
+	OwnerID       string     `json:"owner_id"`       // Subject of the identity that created the alias, if any
+	ExpiresAt     *time.Time `json:"expires_at"`     // Instant after which the alias stops redirecting, nil if it never expires
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// identifies an existing alias that can be reused instead of creating a new one.
+type OriginalUrlLookup struct {
+	TenantID      string
+	NormalizedUrl string
+	RedirectCode  int
+	OwnerID       string
+	ExpiresAt     *time.Time
+}
+
+// position after which a listing continues. aliases are listed newest first,
+// ties on created_at are broken by alias in byte order.
+type UrlAliasCursor struct {
+	CreatedAt time.Time
+	Alias     string
+}
+
+// filters the aliases of an export.
+type UrlAliasExportFilter struct {
+	// exports the aliases of every tenant if empty.
+	TenantID string
+	// exports aliases created at or after CreatedFrom, if set.
+	CreatedFrom *time.Time
+	// exports aliases created before CreatedTo, if set.
+	CreatedTo *time.Time
+}
+
+// defines how imported aliases that already exist in their tenant are handled.
+type ImportConflictPolicy string
+
+const (
+	// keeps the existing alias.
+	ImportConflictSkip ImportConflictPolicy = "skip"
+	// replaces the destination, redirect code, owner and expiry of the existing alias.
+	ImportConflictOverwrite ImportConflictPolicy = "overwrite"
+	// aborts the import if an existing alias differs from the imported one.
+	ImportConflictFail ImportConflictPolicy = "fail"
+)
+
+// ImportConflictPolicies lists the supported conflict policies.
+var ImportConflictPolicies = []ImportConflictPolicy{ImportConflictSkip, ImportConflictOverwrite, ImportConflictFail}
+
+// counts the outcomes of an import.
+type UrlAliasImportResult struct {
+	Inserted int
+	Updated  int
+	// aliases that already existed, and were kept as they were.
+	Skipped int
+	// tenant qualified keys of the updated aliases.
+	UpdatedKeys []string
+}
+
+// reports an imported alias that differs from an existing one under ImportConflictFail.
+type UrlAliasConflictError struct {
+	TenantID string
+	Alias    string
+}
+
+func (e *UrlAliasConflictError) Error() string {
+	return fmt.Sprintf("alias '%s' of tenant '%s' already exists with different values", e.Alias, e.TenantID)
 }
 
 // defines the interface for short URL data access operations.
 type UrlAliasDao interface {
 	// creates a new UrlAlias entry in the database.
-	CreateUrlAlias(ctx context.Context, alias string, originalUrl string) (*UrlAlias, error)
+	// only TenantID, Alias, OriginalURL, NormalizedURL, RedirectCode, OwnerID and ExpiresAt are read from urlAlias.
+	CreateUrlAlias(ctx context.Context, urlAlias *UrlAlias) (*UrlAlias, error)
+
+	// creates the UrlAlias entries, using one multi-row INSERT per shard.
+	// aliases that already exist in the tenant are skipped, and are missing from the returned slice,
+	// as are the aliases of shards that failed. the errors of failed shards are joined.
+	CreateUrlAliases(ctx context.Context, urlAliases []UrlAlias) ([]UrlAlias, error)
+
+	// retrieves a short URL entry of the tenant from the database by its alias.
+	FindByAlias(ctx context.Context, tenantID string, alias string) (*UrlAlias, error)
+
+	// deletes the alias of the tenant. returns false if it didn't exist.
+	DeleteUrlAlias(ctx context.Context, tenantID string, alias string) (bool, error)
+
+	// retries a short URL entry from the DB matching the lookup.
+	// original URLs are compared in their normalized form.
+	FindByOriginalUrl(ctx context.Context, lookup OriginalUrlLookup) (*UrlAlias, error)
+
+	// retrieves the aliases of the owner within the tenant whose normalized URL is one of normalizedUrls.
+	FindByNormalizedUrls(ctx context.Context, tenantID string, ownerID string, normalizedUrls []string) ([]UrlAlias, error)
+
+	// lists the aliases of the owner within the tenant across all shards, newest first.
+	// returns at most limit aliases following the cursor, or from the start if cursor is nil.
+	ListByOwner(ctx context.Context, tenantID string, ownerID string, cursor *UrlAliasCursor, limit int) ([]UrlAlias, error)
+
+	// streams the aliases matching the filter to fn, one shard after the other. aliases are ordered
+	// by creation within a shard only. stops at the first error, including those returned by fn.
+	ExportUrlAliases(ctx context.Context, filter UrlAliasExportFilter, fn func(urlAlias *UrlAlias) error) error
 
-	// retrieves a short URL entry from the database by its alias.
-	FindByAlias(ctx context.Context, alias string) (*UrlAlias, error)
+	// writes the aliases with their CreatedAt, if set, resolving aliases that already exist with the policy.
+	// importing the same aliases again leaves them unchanged. an alias must appear at most once.
+	// under ImportConflictFail nothing is written if any alias conflicts, and a *UrlAliasConflictError is returned.
+	ImportUrlAliases(ctx context.Context, urlAliases []UrlAlias, policy ImportConflictPolicy) (*UrlAliasImportResult, error)
 
-	// retries a short URL entry from the DB by its original URL.
-	FindByOriginalUrl(ctx context.Context, originalUrl string) (*UrlAlias, error)
+	// recomputes the normalized URL of every alias with normalize, e.g. once the normalization rules
+	// changed, and returns how many aliases were updated. aliases whose URL can't be normalized are
+	// left as they are. a failure on one shard doesn't stop the others, their errors are joined.
+	RenormalizeUrlAliases(ctx context.Context, normalize func(originalURL string) (string, error)) (int, error)
 }
 
+// columns selected for every UrlAlias, in the order expected by scanUrlAlias.
+const urlAliasColumns = `tenant_id, alias, original_url, normalized_url, redirect_code, owner_id, expires_at, created_at, updated_at`
+
 // urlAliasDaoImpl is the concrete implementation of UrlAliasDao.
 type urlAliasDaoImpl struct {
 	connManager *db.ConnectionManager
@@ -41,79 +145,228 @@ func NewUrlAliasDao(cm *db.ConnectionManager) UrlAliasDao {
 	}
 }
 
+// scans a row of urlAliasColumns.
+func scanUrlAlias(row interface{ Scan(dest ...any) error }) (*UrlAlias, error) {
+	var urlAlias UrlAlias
+	var ownerID sql.NullString
+	var expiresAt sql.NullTime
+
+	err := row.Scan(
+		&urlAlias.TenantID,
+		&urlAlias.Alias,
+		&urlAlias.OriginalURL,
+		&urlAlias.NormalizedURL,
+		&urlAlias.RedirectCode,
+		&ownerID,
+		&expiresAt,
+		&urlAlias.CreatedAt,
+		&urlAlias.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	urlAlias.OwnerID = ownerID.String
+	if expiresAt.Valid {
+		urlAlias.ExpiresAt = &expiresAt.Time
+	}
+	return &urlAlias, nil
+}
+
 // creates a new url_alias row with provided .
-func (d *urlAliasDaoImpl) CreateUrlAlias(ctx context.Context, alias string, originalUrl string) (*UrlAlias, error) {
+func (d *urlAliasDaoImpl) CreateUrlAlias(ctx context.Context, urlAlias *UrlAlias) (*UrlAlias, error) {
 	if d.connManager == nil {
 		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
 	}
-	shardDB, err := d.connManager.GetShardByShardKey(alias) // Use alias as sharding key
+	tenantID := tenantOrDefault(urlAlias.TenantID)
+	shardKey := AliasKey(tenantID, urlAlias.Alias)
+	shardDB, err := d.connManager.GetShardByShardKey(shardKey) // Use tenant qualified alias as sharding key
 	if err != nil {
-		return nil, fmt.Errorf("failed to get shard for key %s: %w", alias, err)
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", shardKey, err)
 	}
 
-	query := `INSERT INTO url_aliases (alias, original_url) VALUES ($1, $2)
-               RETURNING alias, original_url, created_at, updated_at`
+	query := `INSERT INTO url_aliases (tenant_id, alias, original_url, normalized_url, redirect_code, owner_id, expires_at)
+               VALUES ($1, $2, $3, $4, $5, $6, $7)
+               RETURNING ` + urlAliasColumns
 
-	var createdUrlAlias UrlAlias
-	err = shardDB.QueryRowContext(ctx, query, alias, originalUrl).Scan(
-		&createdUrlAlias.Alias,
-		&createdUrlAlias.OriginalURL,
-		&createdUrlAlias.CreatedAt,
-		&createdUrlAlias.UpdatedAt,
-	)
+	createdUrlAlias, err := scanUrlAlias(shardDB.QueryRowContext(ctx, query,
+		tenantID,
+		urlAlias.Alias,
+		urlAlias.OriginalURL,
+		urlAlias.NormalizedURL,
+		urlAlias.RedirectCode,
+		nullableString(urlAlias.OwnerID),
+		nullableTime(urlAlias.ExpiresAt),
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create URL Alias: %w", err)
 	}
-	return &createdUrlAlias, nil
+	return createdUrlAlias, nil
+}
+
+// number of columns written per url_alias row.
+const urlAliasInsertColumns = 7
+
+// maximum number of rows written by one INSERT, keeping the number of parameters well below the
+// limit of PostgreSQL.
+const maxUrlAliasInsertRows = 1000
+
+func (d *urlAliasDaoImpl) CreateUrlAliases(ctx context.Context, urlAliases []UrlAlias) ([]UrlAlias, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	aliasesByShard := make(map[*sql.DB][]UrlAlias)
+	for _, urlAlias := range urlAliases {
+		urlAlias.TenantID = tenantOrDefault(urlAlias.TenantID)
+		shardKey := AliasKey(urlAlias.TenantID, urlAlias.Alias)
+		shardDB, err := d.connManager.GetShardByShardKey(shardKey) // Use tenant qualified alias as sharding key
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shard for key %s: %w", shardKey, err)
+		}
+		aliasesByShard[shardDB] = append(aliasesByShard[shardDB], urlAlias)
+	}
+
+	var created []UrlAlias
+	var errs []error
+	for shardDB, shardAliases := range aliasesByShard {
+		for start := 0; start < len(shardAliases); start += maxUrlAliasInsertRows {
+			end := min(start+maxUrlAliasInsertRows, len(shardAliases))
+
+			batch, err := insertUrlAliasBatch(ctx, shardDB, shardAliases[start:end])
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			created = append(created, batch...)
+		}
+	}
+	return created, errors.Join(errs...)
+}
+
+// inserts all the given aliases into the provided shard with one statement.
+func insertUrlAliasBatch(ctx context.Context, shardDB *sql.DB, urlAliases []UrlAlias) ([]UrlAlias, error) {
+	placeholders := make([]string, len(urlAliases))
+	args := make([]interface{}, 0, len(urlAliases)*urlAliasInsertColumns)
+
+	for i, urlAlias := range urlAliases {
+		base := i * urlAliasInsertColumns
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7)
+		args = append(args,
+			urlAlias.TenantID,
+			urlAlias.Alias,
+			urlAlias.OriginalURL,
+			urlAlias.NormalizedURL,
+			urlAlias.RedirectCode,
+			nullableString(urlAlias.OwnerID),
+			nullableTime(urlAlias.ExpiresAt),
+		)
+	}
+
+	query := `INSERT INTO url_aliases (tenant_id, alias, original_url, normalized_url, redirect_code, owner_id, expires_at)
+               VALUES ` + strings.Join(placeholders, ", ") + `
+               ON CONFLICT (tenant_id, alias) DO NOTHING
+               RETURNING ` + urlAliasColumns
+
+	rows, err := shardDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create URL Aliases: %w", err)
+	}
+	defer rows.Close()
+
+	var created []UrlAlias
+	for rows.Next() {
+		urlAlias, err := scanUrlAlias(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan Alias: %w", err)
+		}
+		created = append(created, *urlAlias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to create URL Aliases: %w", err)
+	}
+	return created, nil
 }
 
 // retrieves a URL Alias entry from the database by its alias
-func (d *urlAliasDaoImpl) FindByAlias(ctx context.Context, shortUrl string) (*UrlAlias, error) {
+func (d *urlAliasDaoImpl) FindByAlias(ctx context.Context, tenantID string, shortUrl string) (*UrlAlias, error) {
 	if d.connManager == nil {
 		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
 	}
-	shardDB, err := d.connManager.GetShardByShardKey(shortUrl) // Use alias as sharding key
+	tenantID = tenantOrDefault(tenantID)
+	shardKey := AliasKey(tenantID, shortUrl)
+	shardDB, err := d.connManager.GetShardByShardKey(shardKey) // Use tenant qualified alias as sharding key
 	if err != nil {
-		return nil, fmt.Errorf("failed to get shard for key %s: %w", shortUrl, err)
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", shardKey, err)
+	}
+	readDB, err := d.connManager.GetReadShardByShardKey(shardKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard for key %s: %w", shardKey, err)
 	}
 
-	query := `SELECT alias, original_url, created_at, updated_at FROM url_aliases WHERE alias = $1`
+	query := `SELECT ` + urlAliasColumns + ` FROM url_aliases WHERE tenant_id = $1 AND alias = $2`
 
-	var fetchedAlias UrlAlias
-	err = shardDB.QueryRowContext(ctx, query, shortUrl).Scan(
-		&fetchedAlias.Alias,
-		&fetchedAlias.OriginalURL,
-		&fetchedAlias.CreatedAt,
-		&fetchedAlias.UpdatedAt,
-	)
+	fetchedAlias, err := scanUrlAlias(readDB.QueryRowContext(ctx, query, tenantID, shortUrl))
+	if err != nil && readDB != shardDB {
+		// a replica may not have caught up with a freshly created alias, or have failed since
+		// its last health check. misses are rare, so they are confirmed on the primary.
+		fetchedAlias, err = scanUrlAlias(shardDB.QueryRowContext(ctx, query, tenantID, shortUrl))
+	}
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get Alias: %w", err)
 	}
-	return &fetchedAlias, nil
+	return fetchedAlias, nil
 }
 
-// retrieves an Alias entry from the DB by its original URL.
+func (d *urlAliasDaoImpl) DeleteUrlAlias(ctx context.Context, tenantID string, alias string) (bool, error) {
+	if d.connManager == nil {
+		return false, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+	tenantID = tenantOrDefault(tenantID)
+	shardKey := AliasKey(tenantID, alias)
+	shardDB, err := d.connManager.GetShardByShardKey(shardKey) // Use tenant qualified alias as sharding key
+	if err != nil {
+		return false, fmt.Errorf("failed to get shard for key %s: %w", shardKey, err)
+	}
+
+	result, err := shardDB.ExecContext(ctx, `DELETE FROM url_aliases WHERE tenant_id = $1 AND alias = $2`, tenantID, alias)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete Alias: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete Alias: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// retrieves an Alias entry from the DB with the lookup's tenant, normalized URL, redirect code, owner and expiry.
 // returns the UrlAlias entry if found, nil otherwise.
 // returns an error if there was an unexpected error in executing the query.
-func (d *urlAliasDaoImpl) FindByOriginalUrl(ctx context.Context, originalUrl string) (*UrlAlias, error) {
+func (d *urlAliasDaoImpl) FindByOriginalUrl(ctx context.Context, lookup OriginalUrlLookup) (*UrlAlias, error) {
 	if d.connManager == nil {
 		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
 	}
 
-	// Search across all shards for the original URL
-	result, err := d.connManager.ForEachWithResult(func(db *sql.DB) (interface{}, error) {
-		query := `SELECT alias, original_url, created_at, updated_at FROM url_aliases WHERE original_url = $1`
+	// Search across all shards for the original URL. replicas are fine, as a lagging one
+	// only means that a new alias is created instead of an existing one being reused.
+	result, err := d.connManager.ForEachReadWithResult(func(db *sql.DB) (interface{}, error) {
+		query := `SELECT ` + urlAliasColumns + ` FROM url_aliases
+                  WHERE normalized_url = $1 AND redirect_code = $2 AND owner_id IS NOT DISTINCT FROM $3 AND tenant_id = $4
+                    AND expires_at IS NOT DISTINCT FROM $5`
 
-		var fetchedAlias UrlAlias
-		err := db.QueryRowContext(ctx, query, originalUrl).Scan(
-			&fetchedAlias.Alias,
-			&fetchedAlias.OriginalURL,
-			&fetchedAlias.CreatedAt,
-			&fetchedAlias.UpdatedAt,
-		)
+		fetchedAlias, err := scanUrlAlias(db.QueryRowContext(ctx, query,
+			lookup.NormalizedUrl,
+			lookup.RedirectCode,
+			nullableString(lookup.OwnerID),
+			tenantOrDefault(lookup.TenantID),
+			nullableTime(lookup.ExpiresAt),
+		))
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -133,6 +386,402 @@ func (d *urlAliasDaoImpl) FindByOriginalUrl(ctx context.Context, originalUrl str
 		return nil, nil
 	}
 
-	alias := result.(UrlAlias)
-	return &alias, nil
+	return result.(*UrlAlias), nil
+}
+
+// aliases are sharded by alias, so every shard is searched.
+func (d *urlAliasDaoImpl) FindByNormalizedUrls(ctx context.Context, tenantID string, ownerID string, normalizedUrls []string) ([]UrlAlias, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	var aliases []UrlAlias
+
+	err := d.connManager.ForEach(func(db *sql.DB) error {
+		query := `SELECT ` + urlAliasColumns + ` FROM url_aliases
+                  WHERE tenant_id = $1 AND owner_id IS NOT DISTINCT FROM $2 AND normalized_url = ANY($3)`
+
+		rows, err := db.QueryContext(ctx, query, tenantOrDefault(tenantID), nullableString(ownerID), pq.Array(normalizedUrls))
+		if err != nil {
+			return fmt.Errorf("failed to query shard: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			urlAlias, err := scanUrlAlias(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan Alias: %w", err)
+			}
+			aliases = append(aliases, *urlAlias)
+		}
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for normalized URLs: %w", err)
+	}
+	return aliases, nil
+}
+
+// aliases are sharded by alias, so every shard is asked for its first `limit` matches
+// and the results are merge-sorted by created_at.
+func (d *urlAliasDaoImpl) ListByOwner(ctx context.Context, tenantID string, ownerID string, cursor *UrlAliasCursor, limit int) ([]UrlAlias, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	var aliases []UrlAlias
+
+	err := d.connManager.ForEach(func(db *sql.DB) error {
+		query := `SELECT ` + urlAliasColumns + ` FROM url_aliases
+                  WHERE owner_id = $1 AND tenant_id = $3
+                  ORDER BY created_at DESC, alias COLLATE "C" DESC
+                  LIMIT $2`
+		args := []interface{}{ownerID, limit, tenantOrDefault(tenantID)}
+
+		if cursor != nil {
+			query = `SELECT ` + urlAliasColumns + ` FROM url_aliases
+                     WHERE owner_id = $1 AND tenant_id = $3 AND (created_at, alias COLLATE "C") < ($4, $5)
+                     ORDER BY created_at DESC, alias COLLATE "C" DESC
+                     LIMIT $2`
+			args = append(args, cursor.CreatedAt, cursor.Alias)
+		}
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query shard: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			urlAlias, err := scanUrlAlias(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan Alias: %w", err)
+			}
+			aliases = append(aliases, *urlAlias)
+		}
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list aliases of owner: %w", err)
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		if !aliases[i].CreatedAt.Equal(aliases[j].CreatedAt) {
+			return aliases[i].CreatedAt.After(aliases[j].CreatedAt)
+		}
+		return aliases[i].Alias > aliases[j].Alias
+	})
+
+	if len(aliases) > limit {
+		aliases = aliases[:limit]
+	}
+	return aliases, nil
+}
+
+func (d *urlAliasDaoImpl) ExportUrlAliases(ctx context.Context, filter UrlAliasExportFilter, fn func(urlAlias *UrlAlias) error) error {
+	if d.connManager == nil {
+		return fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.TenantID != "" {
+		addCondition("tenant_id = $%d", filter.TenantID)
+	}
+	if filter.CreatedFrom != nil {
+		addCondition("created_at >= $%d", filter.CreatedFrom.UTC())
+	}
+	if filter.CreatedTo != nil {
+		addCondition("created_at < $%d", filter.CreatedTo.UTC())
+	}
+
+	query := `SELECT ` + urlAliasColumns + ` FROM url_aliases`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at, tenant_id, alias`
+
+	// ForEachWithResult stops at the first failing shard, as the output of fn can't be taken back.
+	_, err := d.connManager.ForEachWithResult(func(db *sql.DB) (interface{}, error) {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query shard: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			urlAlias, err := scanUrlAlias(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan Alias: %w", err)
+			}
+			if err := fn(urlAlias); err != nil {
+				return nil, err
+			}
+		}
+		return nil, rows.Err()
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to export aliases: %w", err)
+	}
+	return nil
+}
+
+// number of columns written per imported url_alias row.
+const urlAliasImportColumns = 8
+
+func (d *urlAliasDaoImpl) ImportUrlAliases(ctx context.Context, urlAliases []UrlAlias, policy ImportConflictPolicy) (*UrlAliasImportResult, error) {
+	if d.connManager == nil {
+		return nil, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	aliasesByShard := make(map[*sql.DB][]UrlAlias)
+	for _, urlAlias := range urlAliases {
+		urlAlias.TenantID = tenantOrDefault(urlAlias.TenantID)
+		shardKey := AliasKey(urlAlias.TenantID, urlAlias.Alias)
+		shardDB, err := d.connManager.GetShardByShardKey(shardKey) // Use tenant qualified alias as sharding key
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shard for key %s: %w", shardKey, err)
+		}
+		aliasesByShard[shardDB] = append(aliasesByShard[shardDB], urlAlias)
+	}
+
+	if policy == ImportConflictFail {
+		// every shard is checked before anything is written.
+		for shardDB, shardAliases := range aliasesByShard {
+			if err := checkUrlAliasConflicts(ctx, shardDB, shardAliases); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := &UrlAliasImportResult{}
+	for shardDB, shardAliases := range aliasesByShard {
+		for start := 0; start < len(shardAliases); start += maxUrlAliasInsertRows {
+			end := min(start+maxUrlAliasInsertRows, len(shardAliases))
+
+			if err := importUrlAliasBatch(ctx, shardDB, shardAliases[start:end], policy, result); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// returns a *UrlAliasConflictError if one of the aliases exists in the shard with different values.
+func checkUrlAliasConflicts(ctx context.Context, shardDB *sql.DB, urlAliases []UrlAlias) error {
+	tenantIDs := make([]string, len(urlAliases))
+	aliases := make([]string, len(urlAliases))
+	imported := make(map[string]UrlAlias, len(urlAliases))
+	for i, urlAlias := range urlAliases {
+		tenantIDs[i] = urlAlias.TenantID
+		aliases[i] = urlAlias.Alias
+		imported[AliasKey(urlAlias.TenantID, urlAlias.Alias)] = urlAlias
+	}
+
+	query := `SELECT ` + urlAliasColumns + ` FROM url_aliases
+              WHERE (tenant_id, alias) IN (SELECT * FROM unnest($1::text[], $2::text[]))`
+
+	rows, err := shardDB.QueryContext(ctx, query, pq.Array(tenantIDs), pq.Array(aliases))
+	if err != nil {
+		return fmt.Errorf("failed to check for existing aliases: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		existing, err := scanUrlAlias(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan Alias: %w", err)
+		}
+		if !sameUrlAliasTarget(existing, imported[AliasKey(existing.TenantID, existing.Alias)]) {
+			return &UrlAliasConflictError{TenantID: existing.TenantID, Alias: existing.Alias}
+		}
+	}
+	return rows.Err()
+}
+
+// returns true if the aliases share the values written by an import, besides the creation time.
+func sameUrlAliasTarget(existing *UrlAlias, imported UrlAlias) bool {
+	sameExpiry := existing.ExpiresAt == nil && imported.ExpiresAt == nil
+	if existing.ExpiresAt != nil && imported.ExpiresAt != nil {
+		sameExpiry = existing.ExpiresAt.Equal(imported.ExpiresAt.UTC().Truncate(time.Microsecond))
+	}
+
+	return sameExpiry &&
+		existing.OriginalURL == imported.OriginalURL &&
+		existing.NormalizedURL == imported.NormalizedURL &&
+		existing.RedirectCode == imported.RedirectCode &&
+		existing.OwnerID == imported.OwnerID
+}
+
+// writes the aliases into the provided shard with one statement, adding the outcomes to result.
+func importUrlAliasBatch(ctx context.Context, shardDB *sql.DB, urlAliases []UrlAlias, policy ImportConflictPolicy, result *UrlAliasImportResult) error {
+	placeholders := make([]string, len(urlAliases))
+	args := make([]interface{}, 0, len(urlAliases)*urlAliasImportColumns)
+
+	for i, urlAlias := range urlAliases {
+		base := i * urlAliasImportColumns
+		placeholders[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, COALESCE($%d::timestamp, CURRENT_TIMESTAMP))",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8)
+
+		var createdAt *time.Time
+		if !urlAlias.CreatedAt.IsZero() {
+			createdAt = &urlAlias.CreatedAt
+		}
+		args = append(args,
+			urlAlias.TenantID,
+			urlAlias.Alias,
+			urlAlias.OriginalURL,
+			urlAlias.NormalizedURL,
+			urlAlias.RedirectCode,
+			nullableString(urlAlias.OwnerID),
+			nullableTime(urlAlias.ExpiresAt),
+			nullableTime(createdAt),
+		)
+	}
+
+	onConflict := `DO NOTHING`
+	if policy == ImportConflictOverwrite {
+		// unchanged aliases aren't updated, so that importing them again is a no-op.
+		onConflict = `DO UPDATE SET original_url = EXCLUDED.original_url, normalized_url = EXCLUDED.normalized_url,
+                          redirect_code = EXCLUDED.redirect_code, owner_id = EXCLUDED.owner_id, expires_at = EXCLUDED.expires_at
+                      WHERE (url_aliases.original_url, url_aliases.normalized_url, url_aliases.redirect_code, url_aliases.owner_id, url_aliases.expires_at)
+                          IS DISTINCT FROM (EXCLUDED.original_url, EXCLUDED.normalized_url, EXCLUDED.redirect_code, EXCLUDED.owner_id, EXCLUDED.expires_at)`
+	}
+
+	// xmax is only set on rows that were updated instead of inserted.
+	query := `INSERT INTO url_aliases (tenant_id, alias, original_url, normalized_url, redirect_code, owner_id, expires_at, created_at)
+               VALUES ` + strings.Join(placeholders, ", ") + `
+               ON CONFLICT (tenant_id, alias) ` + onConflict + `
+               RETURNING tenant_id, alias, xmax = 0`
+
+	rows, err := shardDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to import URL Aliases: %w", err)
+	}
+	defer rows.Close()
+
+	written := 0
+	for rows.Next() {
+		var tenantID, alias string
+		var inserted bool
+		if err := rows.Scan(&tenantID, &alias, &inserted); err != nil {
+			return fmt.Errorf("failed to scan imported Alias: %w", err)
+		}
+
+		written++
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+			result.UpdatedKeys = append(result.UpdatedKeys, AliasKey(tenantID, alias))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to import URL Aliases: %w", err)
+	}
+
+	result.Skipped += len(urlAliases) - written
+	return nil
+}
+
+func tenantOrDefault(tenantID string) string {
+	if tenantID == "" {
+		return DefaultTenantID
+	}
+	return tenantID
+}
+
+// timestamps are stored in UTC.
+func nullableTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// number of aliases read and updated at once while renormalizing a shard.
+const renormalizeBatchSize = 1000
+
+func (d *urlAliasDaoImpl) RenormalizeUrlAliases(ctx context.Context, normalize func(originalURL string) (string, error)) (int, error) {
+	if d.connManager == nil {
+		return 0, fmt.Errorf("ConnectionManager is not initialized in DAO")
+	}
+
+	updated := 0
+	err := d.connManager.ForEach(func(db *sql.DB) error {
+		count, err := renormalizeShard(ctx, db, normalize)
+		updated += count
+		if err != nil {
+			return fmt.Errorf("failed to renormalize aliases: %w", err)
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// renormalizes the aliases of one shard in batches, paging through them by key so that rows
+// updated meanwhile are neither skipped nor read twice.
+func renormalizeShard(ctx context.Context, shardDB *sql.DB, normalize func(originalURL string) (string, error)) (int, error) {
+	updated := 0
+	lastTenantID, lastAlias := "", ""
+
+	for {
+		rows, err := shardDB.QueryContext(ctx, `SELECT tenant_id, alias, original_url, normalized_url FROM url_aliases
+                  WHERE (tenant_id, alias) > ($1, $2)
+                  ORDER BY tenant_id, alias
+                  LIMIT $3`, lastTenantID, lastAlias, renormalizeBatchSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to query shard: %w", err)
+		}
+
+		var tenantIDs, aliases, normalizedUrls []string
+		read := 0
+		for rows.Next() {
+			var originalUrl, normalizedUrl string
+			if err := rows.Scan(&lastTenantID, &lastAlias, &originalUrl, &normalizedUrl); err != nil {
+				rows.Close()
+				return updated, fmt.Errorf("failed to scan Alias: %w", err)
+			}
+			read++
+
+			renormalized, err := normalize(originalUrl)
+			if err != nil || renormalized == normalizedUrl {
+				continue
+			}
+			tenantIDs = append(tenantIDs, lastTenantID)
+			aliases = append(aliases, lastAlias)
+			normalizedUrls = append(normalizedUrls, renormalized)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, fmt.Errorf("failed to query shard: %w", err)
+		}
+
+		if len(aliases) > 0 {
+			result, err := shardDB.ExecContext(ctx, `UPDATE url_aliases AS u SET normalized_url = v.normalized_url
+                      FROM unnest($1::varchar[], $2::varchar[], $3::varchar[]) AS v(tenant_id, alias, normalized_url)
+                      WHERE u.tenant_id = v.tenant_id AND u.alias = v.alias`,
+				pq.Array(tenantIDs), pq.Array(aliases), pq.Array(normalizedUrls))
+			if err != nil {
+				return updated, fmt.Errorf("failed to update normalized URLs: %w", err)
+			}
+			count, err := result.RowsAffected()
+			if err != nil {
+				return updated, fmt.Errorf("failed to update normalized URLs: %w", err)
+			}
+			updated += int(count)
+		}
+
+		if read < renormalizeBatchSize {
+			return updated, nil
+		}
+	}
 }
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE alias_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    alias VARCHAR(8) NOT NULL,
+    clicked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer VARCHAR,
+    user_agent VARCHAR,
+    country VARCHAR(2),
+    ip_hash VARCHAR(64)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_alias_clicks_alias_clicked_at ON alias_clicks (alias, clicked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_alias_clicks_alias_clicked_at;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alias_clicks;
+-- +goose StatementEnd
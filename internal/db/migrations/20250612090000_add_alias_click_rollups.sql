@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE alias_click_rollups (
+    alias VARCHAR(8) NOT NULL,
+    granularity VARCHAR(8) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    clicks BIGINT NOT NULL,
+    unique_visitors BIGINT NOT NULL,
+    PRIMARY KEY (alias, granularity, bucket)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE alias_click_dimension_rollups (
+    alias VARCHAR(8) NOT NULL,
+    day TIMESTAMP NOT NULL,
+    dimension VARCHAR(16) NOT NULL,
+    value VARCHAR NOT NULL,
+    clicks BIGINT NOT NULL,
+    PRIMARY KEY (alias, day, dimension, value)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE alias_click_rollup_state (
+    id INT PRIMARY KEY,
+    rolled_up_until TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO alias_click_rollup_state (id, rolled_up_until) VALUES (1, '1970-01-01 00:00:00');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alias_click_rollup_state;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alias_click_dimension_rollups;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alias_click_rollups;
+-- +goose StatementEnd
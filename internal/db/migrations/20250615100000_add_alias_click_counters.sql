@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE alias_click_counters (
+    alias VARCHAR(8) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    clicks BIGINT NOT NULL,
+    unique_visitors BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (alias, bucket)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alias_click_counters;
+-- +goose StatementEnd
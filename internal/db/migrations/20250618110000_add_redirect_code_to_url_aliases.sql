@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+ADD COLUMN redirect_code SMALLINT NOT NULL DEFAULT 302
+CHECK (redirect_code IN (301, 302, 307, 308));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP COLUMN IF EXISTS redirect_code;
+-- +goose StatementEnd
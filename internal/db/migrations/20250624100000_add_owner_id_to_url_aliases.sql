@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+ADD COLUMN owner_id VARCHAR;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_url_aliases_owner_created_at
+ON url_aliases (owner_id, created_at DESC, alias COLLATE "C" DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_url_aliases_owner_created_at;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP COLUMN IF EXISTS owner_id;
+-- +goose StatementEnd
@@ -0,0 +1,86 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tenants (
+    id VARCHAR(32) PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    domain VARCHAR(253) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER update_tenants_updated_at
+BEFORE UPDATE ON tenants
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- existing aliases belong to the default tenant, and aliases are only unique within a tenant.
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP CONSTRAINT short_urls_pkey,
+ADD PRIMARY KEY (tenant_id, alias);
+-- +goose StatementEnd
+
+-- API keys are only valid on the domain of their tenant, existing keys on the default one.
+-- +goose StatementBegin
+ALTER TABLE api_keys
+ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+-- +goose StatementEnd
+
+-- analytics are keyed by the tenant qualified alias key ("<tenant>/<alias>"),
+-- which is longer than a bare alias.
+-- +goose StatementBegin
+ALTER TABLE alias_clicks ALTER COLUMN alias TYPE VARCHAR(41);
+ALTER TABLE alias_click_rollups ALTER COLUMN alias TYPE VARCHAR(41);
+ALTER TABLE alias_click_dimension_rollups ALTER COLUMN alias TYPE VARCHAR(41);
+ALTER TABLE alias_click_counters ALTER COLUMN alias TYPE VARCHAR(41);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM alias_clicks WHERE alias LIKE '%/%';
+DELETE FROM alias_click_rollups WHERE alias LIKE '%/%';
+DELETE FROM alias_click_dimension_rollups WHERE alias LIKE '%/%';
+DELETE FROM alias_click_counters WHERE alias LIKE '%/%';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE alias_clicks ALTER COLUMN alias TYPE VARCHAR(8);
+ALTER TABLE alias_click_rollups ALTER COLUMN alias TYPE VARCHAR(8);
+ALTER TABLE alias_click_dimension_rollups ALTER COLUMN alias TYPE VARCHAR(8);
+ALTER TABLE alias_click_counters ALTER COLUMN alias TYPE VARCHAR(8);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM url_aliases WHERE tenant_id <> 'default';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP CONSTRAINT url_aliases_pkey,
+ADD CONSTRAINT short_urls_pkey PRIMARY KEY (alias);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP COLUMN IF EXISTS tenant_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM api_keys WHERE tenant_id <> 'default';
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_tenants_updated_at ON tenants;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tenants;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+ADD COLUMN expires_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP COLUMN IF EXISTS expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- existing aliases start with their original URL as the normalized form. SQL can't apply the
+-- normalizer, so `alias renormalize` recomputes it once the migration ran; until then they are
+-- only reused for URLs that were already canonical.
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+ADD COLUMN normalized_url VARCHAR;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE url_aliases SET normalized_url = original_url;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+ALTER COLUMN normalized_url SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_url_aliases_tenant_normalized_url
+ON url_aliases (tenant_id, normalized_url);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_url_aliases_tenant_normalized_url;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE url_aliases
+DROP COLUMN IF EXISTS normalized_url;
+-- +goose StatementEnd
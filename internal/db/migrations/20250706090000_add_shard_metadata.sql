@@ -0,0 +1,15 @@
+-- +goose Up
+-- a single row recording which shard the database is, stamped the first time the server starts with it.
+-- +goose StatementBegin
+CREATE TABLE shard_metadata (
+    singleton BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (singleton),
+    shard_id INTEGER NOT NULL,
+    shard_count INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shard_metadata;
+-- +goose StatementEnd
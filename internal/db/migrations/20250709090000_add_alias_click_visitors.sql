@@ -0,0 +1,22 @@
+-- +goose Up
+-- the distinct visitors of every alias per day, so that the unique visitors of a long range are
+-- counted from one row per visitor and day rather than from every raw click.
+-- +goose StatementBegin
+CREATE TABLE alias_click_visitors (
+    alias VARCHAR(41) NOT NULL,
+    day TIMESTAMP NOT NULL,
+    ip_hash VARCHAR(64) NOT NULL,
+    PRIMARY KEY (alias, day, ip_hash)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO alias_click_visitors (alias, day, ip_hash)
+SELECT DISTINCT alias, date_trunc('day', clicked_at), ip_hash
+FROM alias_clicks WHERE ip_hash IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alias_click_visitors;
+-- +goose StatementEnd
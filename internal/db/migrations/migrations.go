@@ -0,0 +1,10 @@
+// Package migrations embeds the goose migrations of the url_aliases schema, so that
+// the binary can migrate its shards without the SQL files being deployed next to it.
+package migrations
+
+import "embed"
+
+// the migrations, at the root of the file system.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// interval at which a migrator waiting for the lock of a shard retries to take it.
+const migrationLockRetryPeriod = 5 * time.Second
+
+// MigrationStatus describes the schema version of a shard.
+type MigrationStatus struct {
+	ShardID int
+	// name of the shard, including its ID.
+	Shard string
+	// version of the last applied migration, 0 if none was applied.
+	Version int64
+	// version of the last known migration.
+	Latest int64
+	// migrations that haven't been applied yet, in the order they would be applied.
+	Pending []string
+}
+
+// Migrator applies and rolls back the goose migrations of the shards.
+// shards are migrated independently, each migration runs in its own transaction.
+//
+// migrations of a shard are applied while holding a Postgres advisory lock on it, so that
+// instances starting together don't migrate the same shard concurrently. the others wait
+// for the lock, and find nothing left to apply once they get it.
+type Migrator struct {
+	cm   *ConnectionManager
+	fsys fs.FS
+	// maximum time to wait for the migration lock of a shard.
+	lockTimeout time.Duration
+}
+
+// creates a Migrator running the migrations found at the root of fsys, waiting up to
+// lockTimeout for another instance to finish migrating a shard.
+func NewMigrator(cm *ConnectionManager, fsys fs.FS, lockTimeout time.Duration) *Migrator {
+	return &Migrator{cm: cm, fsys: fsys, lockTimeout: lockTimeout}
+}
+
+// returns a locker retrying to take the advisory lock until the lock timeout has passed.
+// advisory locks are scoped to a database, so the same lock id is used on every shard.
+func (m *Migrator) sessionLocker() (lock.SessionLocker, error) {
+	period := migrationLockRetryPeriod
+	if m.lockTimeout < period {
+		period = time.Second
+	}
+	retries := uint64((m.lockTimeout + period - 1) / period)
+	if retries < 1 {
+		retries = 1
+	}
+
+	return lock.NewPostgresSessionLocker(
+		lock.WithLockTimeout(uint64(period/time.Second), retries),
+	)
+}
+
+// providers aren't closed, as that would close the shard's connection.
+func (m *Migrator) provider(shardID int) (*goose.Provider, string, error) {
+	db, err := m.cm.shardByID(shardID)
+	if err != nil {
+		return nil, "", err
+	}
+	shardName := m.cm.shardNames[shardID]
+
+	locker, err := m.sessionLocker()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create migration lock for shard %s: %w", shardName, err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, m.fsys, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load migrations for shard %s: %w", shardName, err)
+	}
+	return provider, shardName, nil
+}
+
+// applies the pending migrations to the shard.
+func (m *Migrator) Up(ctx context.Context, shardID int) error {
+	provider, shardName, err := m.provider(shardID)
+	if err != nil {
+		return err
+	}
+
+	// another instance holding the lock shows up as a failure to take it once the timeout has passed.
+	results, err := provider.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations to shard %s (waited up to %s for the migration lock): %w", shardName, m.lockTimeout, err)
+	}
+	log.Printf("successfully applied %d migrations to shard: %s", len(results), shardName)
+	return nil
+}
+
+// applies the pending migrations to every shard. a failing shard doesn't keep the others
+// from being migrated; the errors of all failed shards are joined.
+func (m *Migrator) UpAll(ctx context.Context) error {
+	log.Printf("applying DB Migrations on all shards using Goose..")
+
+	var errs []error
+	for shardID := range m.cm.shards {
+		if err := m.Up(ctx, shardID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	log.Println("all shards processed for migrations.")
+	return nil
+}
+
+// rolls the last applied migration of the shard back.
+func (m *Migrator) Down(ctx context.Context, shardID int) error {
+	provider, shardName, err := m.provider(shardID)
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.Down(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to roll back migration of shard %s: %w", shardName, err)
+	}
+	log.Printf("successfully rolled back %s on shard: %s", path.Base(result.Source.Path), shardName)
+	return nil
+}
+
+// rolls back the migrations of the shard newer than version. version 0 rolls back every migration.
+func (m *Migrator) DownTo(ctx context.Context, shardID int, version int64) error {
+	provider, shardName, err := m.provider(shardID)
+	if err != nil {
+		return err
+	}
+
+	results, err := provider.DownTo(ctx, version)
+	if err != nil {
+		return fmt.Errorf("failed to roll back shard %s to version %d: %w", shardName, version, err)
+	}
+	log.Printf("successfully rolled back %d migrations on shard %s to version %d", len(results), shardName, version)
+	return nil
+}
+
+// returns the applied version and the pending migrations of the shard.
+func (m *Migrator) Status(ctx context.Context, shardID int) (*MigrationStatus, error) {
+	provider, shardName, err := m.provider(shardID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration status of shard %s: %w", shardName, err)
+	}
+
+	version, latest, err := provider.GetVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration version of shard %s: %w", shardName, err)
+	}
+
+	status := &MigrationStatus{ShardID: shardID, Shard: shardName, Version: version, Latest: latest}
+	for _, migration := range statuses {
+		if migration.State == goose.StatePending {
+			status.Pending = append(status.Pending, path.Base(migration.Source.Path))
+		}
+	}
+	return status, nil
+}
+
+// returns the status of every shard, by shard ID.
+func (m *Migrator) StatusAll(ctx context.Context) ([]*MigrationStatus, error) {
+	statuses := make([]*MigrationStatus, 0, len(m.cm.shards))
+	for shardID := range m.cm.shards {
+		status, err := m.Status(ctx, shardID)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// checks that every shard is on the same schema version, as queries spanning shards assume
+// the same columns everywhere. returns the common version of the shards.
+func (m *Migrator) CheckVersions(ctx context.Context) (int64, error) {
+	statuses, err := m.StatusAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+
+	shardsByVersion := make(map[int64][]string)
+	for _, status := range statuses {
+		shardsByVersion[status.Version] = append(shardsByVersion[status.Version], status.Shard)
+	}
+
+	if len(shardsByVersion) > 1 {
+		versions := make([]string, 0, len(shardsByVersion))
+		for version, shards := range shardsByVersion {
+			versions = append(versions, fmt.Sprintf("%d on %s", version, strings.Join(shards, ", ")))
+		}
+		sort.Strings(versions)
+		return 0, fmt.Errorf("shards are on different schema versions: %s", strings.Join(versions, "; "))
+	}
+
+	status := statuses[0]
+	if len(status.Pending) > 0 {
+		log.Printf("Warning: %d migrations haven't been applied to the shards yet, the latest is version %d", len(status.Pending), status.Latest)
+	}
+	if status.Version > status.Latest {
+		log.Printf("Warning: the shards are on schema version %d, newer than the latest known migration %d", status.Version, status.Latest)
+	}
+	return status.Version, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// records the ID of the shard and the number of shards, unless the shard already has them.
+// instances starting together insert the same values, so only the first insert is kept.
+const stampShardQuery = `INSERT INTO shard_metadata (shard_id, shard_count) VALUES ($1, $2)
+    ON CONFLICT (singleton) DO NOTHING`
+
+// records the ID of the shard and the number of shards, replacing the recorded ones.
+const restampShardQuery = `INSERT INTO shard_metadata (shard_id, shard_count) VALUES ($1, $2)
+    ON CONFLICT (singleton) DO UPDATE SET shard_id = EXCLUDED.shard_id, shard_count = EXCLUDED.shard_count`
+
+const shardMetadataQuery = `SELECT shard_id, shard_count FROM shard_metadata`
+
+// checks that every shard is the shard it is configured as. shards record their ID and the
+// number of shards the first time they are checked. a shard configured with another ID, e.g.
+// as the shards were reordered, would get keys that hash to another shard, so does every
+// key once shards are added or removed; both are reported as errors.
+//
+// needs the shard_metadata table, added by the migrations.
+func (cm *ConnectionManager) VerifyTopology(ctx context.Context) error {
+	var errs []error
+	for shardID, db := range cm.shards {
+		if err := cm.verifyShard(ctx, db, shardID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (cm *ConnectionManager) verifyShard(ctx context.Context, db *sql.DB, shardID int) error {
+	shardName := cm.shardNames[shardID]
+
+	result, err := db.ExecContext(ctx, stampShardQuery, shardID, len(cm.shards))
+	if err != nil {
+		return fmt.Errorf("failed to record the ID of shard %s, are the migrations applied? %w", shardName, err)
+	}
+	if stamped, err := result.RowsAffected(); err == nil && stamped > 0 {
+		log.Printf("shard %s: recorded as shard %d of %d", shardName, shardID, len(cm.shards))
+		return nil
+	}
+
+	var recordedID, recordedCount int
+	if err := db.QueryRowContext(ctx, shardMetadataQuery).Scan(&recordedID, &recordedCount); err != nil {
+		return fmt.Errorf("failed to read the ID of shard %s: %w", shardName, err)
+	}
+
+	if recordedID != shardID || recordedCount != len(cm.shards) {
+		return fmt.Errorf("shard %s is configured as shard %d of %d, but the database is shard %d of %d",
+			shardName, shardID, len(cm.shards), recordedID, recordedCount)
+	}
+	return nil
+}
+
+// records the configured ID and the number of shards on every shard, replacing what the shards
+// recorded before. meant for after shards were added or removed on purpose and the aliases were
+// moved to the shards they now hash to; VerifyTopology accepts the new topology from then on.
+func (cm *ConnectionManager) StampTopology(ctx context.Context) error {
+	var errs []error
+	for shardID, db := range cm.shards {
+		shardName := cm.shardNames[shardID]
+		if _, err := db.ExecContext(ctx, restampShardQuery, shardID, len(cm.shards)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record the ID of shard %s: %w", shardName, err))
+			continue
+		}
+		log.Printf("shard %s: recorded as shard %d of %d", shardName, shardID, len(cm.shards))
+	}
+	return errors.Join(errs...)
+}
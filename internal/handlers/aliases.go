@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/auth"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+)
+
+const (
+	// number of aliases returned per page by default.
+	defaultAliasPageSize = 50
+	maxAliasPageSize     = 200
+	// value of the owner parameter referring to the caller.
+	ownerMe = "me"
+)
+
+// AliasListItem is a single alias in an alias listing.
+//
+// @Description A single alias owned by the caller.
+type AliasListItem struct {
+	Alias        string     `json:"alias" example:"aBcDeFg1"`
+	ShortUrl     string     `json:"shortUrl" example:"https://sho.rt/aBcDeFg1"`
+	OriginalUrl  string     `json:"originalUrl" example:"https://example.com/very/long/url/to/shorten"`
+	RedirectCode int        `json:"redirectCode" example:"302"`
+	CreatedAt    time.Time  `json:"createdAt" example:"2025-06-24T10:00:00Z"`
+	ExpiresAt    *time.Time `json:"expiresAt" example:"2025-12-31T23:59:59Z"` // Null if the alias never expires
+}
+
+// ListAliasesResponse defines the response body of the alias listing endpoint.
+//
+// @Description A page of aliases, newest first.
+type ListAliasesResponse struct {
+	Items      []AliasListItem `json:"items"`
+	NextCursor string          `json:"nextCursor,omitempty" example:"MjAyNS0wNi0yNFQxMDowMDowMFp8YUJjRGVGZzE"` // Pass as `cursor` to fetch the next page. Omitted on the last page
+}
+
+// ListAliasesHandler lists the aliases owned by the caller, newest first.
+//
+// Aliases are sharded by alias, so every shard is queried and the results are
+// merged. Pages are addressed with the opaque cursor returned in `nextCursor`.
+//
+// @Summary List owned aliases
+// @Description Lists the aliases created by the caller on the tenant of the request host, newest first. Callers with the admin scope may pass another owner's subject instead of `me`.
+// @Tags urls
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param owner query string true "Owner of the aliases" default(me)
+// @Param limit query int false "Number of aliases per page" default(50)
+// @Param cursor query string false "Cursor returned as `nextCursor` by the previous page"
+// @Success 200 {object} ListAliasesResponse "A page of aliases"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Listing another owner's aliases requires the admin scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/aliases [get]
+func ListAliasesHandler(w http.ResponseWriter, r *http.Request) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("ListAliasesHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "ListAliasesHandler: Error accessing AppEnv.")
+		return
+	}
+
+	identity := middleware.IdentityFromContext(r.Context())
+	if identity == nil {
+		log.Printf("ListAliasesHandler: Request is not authenticated.")
+		SendInternalServerError(w, "ListAliasesHandler: Request is not authenticated.")
+		return
+	}
+
+	tenant := middleware.TenantFromContext(r.Context())
+	params := r.URL.Query()
+
+	ownerID := params.Get("owner")
+	switch {
+	case ownerID == "":
+		SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: "'owner' is required, use 'me' for the caller's own aliases"}, http.StatusBadRequest)
+		return
+	case ownerID == ownerMe:
+		ownerID = identity.Subject
+	case ownerID != identity.Subject && !identity.HasScope(auth.ScopeAdmin):
+		SendErrorResponse(w, ErrorResponse{Error: "Forbidden", Message: "Listing another owner's aliases requires the 'admin' scope."}, http.StatusForbidden)
+		return
+	}
+
+	limit := defaultAliasPageSize
+	if rawLimit := params.Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 1 || parsed > maxAliasPageSize {
+			SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: fmt.Sprintf("'limit' must be between 1 and %d", maxAliasPageSize)}, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	var cursor *dao.UrlAliasCursor
+	if rawCursor := params.Get("cursor"); rawCursor != "" {
+		decoded, err := decodeAliasCursor(rawCursor)
+		if err != nil {
+			SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		cursor = decoded
+	}
+
+	// one extra alias is fetched to tell whether there is a next page.
+	aliases, err := appEnv.UrlAliasDao.ListByOwner(r.Context(), tenant.ID, ownerID, cursor, limit+1)
+	if err != nil {
+		log.Printf("ListAliasesHandler: Unexpected error while listing aliases : %s.", err)
+		SendInternalServerError(w, "ListAliasesHandler: Unexpected error while listing aliases.")
+		return
+	}
+
+	response := &ListAliasesResponse{Items: make([]AliasListItem, 0, len(aliases))}
+	if len(aliases) > limit {
+		aliases = aliases[:limit]
+		last := aliases[limit-1]
+		response.NextCursor = encodeAliasCursor(&dao.UrlAliasCursor{CreatedAt: last.CreatedAt, Alias: last.Alias})
+	}
+
+	for _, alias := range aliases {
+		response.Items = append(response.Items, AliasListItem{
+			Alias:        alias.Alias,
+			ShortUrl:     shortUrlFor(r, appEnv, tenant, alias.Alias),
+			OriginalUrl:  alias.OriginalURL,
+			RedirectCode: alias.RedirectCode,
+			CreatedAt:    alias.CreatedAt,
+			ExpiresAt:    alias.ExpiresAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// cursors are the created_at and alias of the last alias on the page, base64 encoded.
+func encodeAliasCursor(cursor *dao.UrlAliasCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.Alias
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAliasCursor(encoded string) (*dao.UrlAliasCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("'cursor' is invalid")
+	}
+
+	createdAt, alias, found := strings.Cut(string(raw), "|")
+	if !found || alias == "" {
+		return nil, fmt.Errorf("'cursor' is invalid")
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("'cursor' is invalid")
+	}
+
+	return &dao.UrlAliasCursor{CreatedAt: parsed, Alias: alias}, nil
+}
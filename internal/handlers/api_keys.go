@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shashwatrathod/url-shortner/internal/auth"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+)
+
+// CreateApiKeyRequest defines the request body for minting an API key.
+//
+// @Description Request body for minting an API key.
+type CreateApiKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100" example:"campaign-tool"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=create read:stats admin" example:"create,read:stats"`
+}
+
+// CreateApiKeyResponse defines the response body for a minted API key.
+//
+// @Description Response body for a minted API key. The key is only returned once.
+type CreateApiKeyResponse struct {
+	ID        string    `json:"id" example:"Ab3dEf6hIj9k"`
+	Key       string    `json:"key" example:"gs_Ab3dEf6hIj9k_0123456789abcdefghijABCDEFGHIJ01"`
+	Name      string    `json:"name" example:"campaign-tool"`
+	TenantID  string    `json:"tenantId" example:"default"`
+	Scopes    []string  `json:"scopes" example:"create,read:stats"`
+	CreatedAt time.Time `json:"createdAt" example:"2025-06-21T09:00:00Z"`
+}
+
+// CreateApiKeyHandler mints a new API key.
+//
+// @Summary Mint an API key
+// @Description Mints a new API key with the given scopes for the tenant of the request's domain. The plaintext key is only returned in this response.
+// @Tags keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param request body CreateApiKeyRequest true "Request body to mint an API key"
+// @Success 201 {object} CreateApiKeyResponse "Successfully minted API key"
+// @Failure 400 {object} middleware.ValidationError "Invalid request payload (validation error)"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the admin scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/keys [post]
+func CreateApiKeyHandler(w http.ResponseWriter, r *http.Request, req CreateApiKeyRequest) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("CreateApiKeyHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "CreateApiKeyHandler: Error accessing AppEnv.")
+		return
+	}
+
+	tenant := middleware.TenantFromContext(r.Context())
+
+	apiKey, key, err := auth.MintAPIKey(r.Context(), appEnv.ApiKeyDao, req.Name, req.Scopes, tenant.ID)
+	if err != nil {
+		log.Printf("CreateApiKeyHandler: Unexpected error while minting API key : %s.", err)
+		SendInternalServerError(w, "CreateApiKeyHandler: Unexpected error while minting API key.")
+		return
+	}
+
+	log.Printf("Minted API key '%s' (%s) of tenant '%s'", apiKey.ID, apiKey.Name, apiKey.TenantID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&CreateApiKeyResponse{
+		ID:        apiKey.ID,
+		Key:       key,
+		Name:      apiKey.Name,
+		TenantID:  apiKey.TenantID,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+	})
+}
+
+// RevokeApiKeyHandler revokes an API key.
+//
+// @Summary Revoke an API key
+// @Description Revokes the API key with the given id. Revoked keys are rejected immediately.
+// @Tags keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key id" example:"Ab3dEf6hIj9k"
+// @Success 204 "API key revoked"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the admin scope"
+// @Failure 404 {object} ErrorResponse "No active API key with the id"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/keys/{id} [delete]
+func RevokeApiKeyHandler(w http.ResponseWriter, r *http.Request) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("RevokeApiKeyHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "RevokeApiKeyHandler: Error accessing AppEnv.")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	revoked, err := appEnv.ApiKeyDao.RevokeApiKey(r.Context(), id)
+	if err != nil {
+		log.Printf("RevokeApiKeyHandler: Unexpected error while revoking API key : %s.", err)
+		SendInternalServerError(w, "RevokeApiKeyHandler: Unexpected error while revoking API key.")
+		return
+	}
+
+	if !revoked {
+		SendErrorResponse(w, ErrorResponse{Error: "Not Found", Message: "No active API key with the requested id was found."}, http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Revoked API key '%s'", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+)
+
+const (
+	// maximum size of a bulk create request body.
+	maxBulkBodyBytes = 16 << 20
+	// number of times aliases colliding with existing ones are regenerated.
+	maxBulkInsertAttempts = 3
+	// number of items checked against the URL policy at once, as checks may resolve host names.
+	bulkCheckConcurrency = 16
+	// time the URL policy checks of a request may take in total.
+	bulkCheckTimeout = 30 * time.Second
+)
+
+// BulkItemError describes why a single item of a bulk request failed.
+//
+// @Description Error of a single item of a bulk request.
+type BulkItemError struct {
+	Error    string   `json:"error" example:"Unprocessable Entity"`
+	Message  string   `json:"message,omitempty" example:"Links to private or internal addresses aren't allowed."`
+	Messages []string `json:"messages,omitempty"`                       // Validation errors, if the item failed validation
+	Rule     string   `json:"rule,omitempty" example:"private_network"` // Violated URL policy rule, if the URL wasn't allowed
+}
+
+// BulkCreateItemResult is the outcome of a single item of a bulk create request.
+//
+// @Description Outcome of a single item of a bulk create request. Exactly one of result and error is set.
+type BulkCreateItemResult struct {
+	Index  int                     `json:"index" example:"0"` // Position of the item in the request, starting at 0
+	Result *CreateUrlAliasResponse `json:"result,omitempty"`
+	Error  *BulkItemError          `json:"error,omitempty"`
+}
+
+// BulkCreateResponse defines the response body of the bulk create endpoint.
+//
+// @Description Per-item outcomes of a bulk create request, in request order.
+type BulkCreateResponse struct {
+	Results      []BulkCreateItemResult `json:"results"`
+	CreatedCount int                    `json:"createdCount" example:"2"`
+	ReusedCount  int                    `json:"reusedCount" example:"1"`
+	FailedCount  int                    `json:"failedCount" example:"0"`
+}
+
+// bulkItem is an item of a bulk request, or the reason it couldn't be parsed.
+type bulkItem struct {
+	request    CreateUrlAliasRequest
+	parseError string
+}
+
+// pendingBulkItem is an item that passed validation and still needs an alias.
+type pendingBulkItem struct {
+	index         int
+	request       CreateUrlAliasRequest
+	normalizedUrl string
+	redirectCode  int
+	key           string
+}
+
+// CreateUrlAliasesBulkHandler creates aliases for many URLs at once.
+//
+// Every item goes through the same validation, URL policy and normalization as a
+// single create, and failures are reported per item instead of failing the whole
+// request. New aliases are written with one multi-row INSERT per shard.
+//
+// @Summary Create URL aliases in bulk
+// @Description Creates or reuses aliases for up to BULK_CREATE_MAX_ITEMS URLs, given as a JSON array of create requests or as a CSV with an `originalUrl` column and optional `redirectCode` and `expiresAt` columns, either as the body or as the `file` of a multipart form.
+// @Tags urls
+// @Accept json
+// @Accept text/csv
+// @Accept mpfd
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param request body []CreateUrlAliasRequest true "URLs to create aliases for"
+// @Success 200 {object} BulkCreateResponse "Per-item results"
+// @Failure 400 {object} ErrorResponse "Malformed or empty request"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the create scope"
+// @Failure 413 {object} ErrorResponse "Too many items or request too large"
+// @Failure 415 {object} ErrorResponse "Unsupported content type"
+// @Failure 429 {object} ErrorResponse "Rate limit exceeded, see the Retry-After header"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/create/bulk [post]
+func CreateUrlAliasesBulkHandler(w http.ResponseWriter, r *http.Request) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("CreateUrlAliasesBulkHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "CreateUrlAliasesBulkHandler: Error accessing AppEnv.")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkBodyBytes)
+
+	items, status, err := parseBulkItems(r)
+	if err != nil {
+		SendErrorResponse(w, ErrorResponse{Error: http.StatusText(status), Message: err.Error()}, status)
+		return
+	}
+
+	if len(items) == 0 {
+		SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: "The request contains no URLs."}, http.StatusBadRequest)
+		return
+	}
+
+	if maxItems := appEnv.Config.AliasConfig.BulkCreateMaxItems; len(items) > maxItems {
+		SendErrorResponse(w, ErrorResponse{Error: "Request Entity Too Large", Message: fmt.Sprintf("At most %d URLs can be created per request.", maxItems)}, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// each URL counts against the create limit like a single create, the request itself having been counted already.
+	if !middleware.ChargeRateLimit(w, r, middleware.RateLimitCreate, int64(len(items)-1)) {
+		return
+	}
+
+	tenant := middleware.TenantFromContext(r.Context())
+	var ownerID string
+	if identity := middleware.IdentityFromContext(r.Context()); identity != nil {
+		ownerID = identity.Subject
+	}
+
+	itemErrors, err := checkBulkItems(r, appEnv, items)
+	if err != nil {
+		log.Printf("CreateUrlAliasesBulkHandler: Unexpected error while checking items : %s.", err)
+		SendInternalServerError(w, "CreateUrlAliasesBulkHandler: Unexpected error while checking the URL policy.")
+		return
+	}
+
+	results := make([]BulkCreateItemResult, len(items))
+	pending := make([]pendingBulkItem, 0, len(items))
+
+	for i, item := range items {
+		results[i].Index = i
+
+		if itemError := itemErrors[i]; itemError != nil {
+			results[i].Error = itemError
+			continue
+		}
+
+		normalizedUrl, err := appEnv.UrlNormalizer.Normalize(item.request.OriginalUrl)
+		if err != nil {
+			results[i].Error = &BulkItemError{Error: "Bad Request", Message: "The URL can't be parsed."}
+			continue
+		}
+
+		redirectCode := item.request.RedirectCode
+		if redirectCode == 0 {
+			redirectCode = appEnv.Config.AliasConfig.DefaultRedirectCode
+		}
+
+		pending = append(pending, pendingBulkItem{
+			index:         i,
+			request:       item.request,
+			normalizedUrl: normalizedUrl,
+			redirectCode:  redirectCode,
+			key:           bulkDedupKey(normalizedUrl, redirectCode, item.request.ExpiresAt),
+		})
+	}
+
+	aliasesByKey, createdKeys, err := resolveBulkAliases(r, appEnv, tenant, ownerID, pending)
+	if err != nil {
+		log.Printf("CreateUrlAliasesBulkHandler: Unexpected error while resolving aliases : %s.", err)
+		SendInternalServerError(w, "CreateUrlAliasesBulkHandler: Unexpected error while resolving aliases.")
+		return
+	}
+
+	response := &BulkCreateResponse{Results: results}
+	for _, item := range pending {
+		urlAlias, found := aliasesByKey[item.key]
+		if !found {
+			results[item.index].Error = &BulkItemError{Error: "Internal Server Error", Message: "The alias couldn't be saved."}
+			continue
+		}
+
+		// items repeating a URL of the same request reuse the alias created for the first one.
+		created := createdKeys[item.key]
+		delete(createdKeys, item.key)
+
+		results[item.index].Result = newCreateUrlAliasResponse(r, appEnv, tenant, urlAlias, created)
+		if created {
+			response.CreatedCount++
+		} else {
+			response.ReusedCount++
+		}
+	}
+	response.FailedCount = len(items) - response.CreatedCount - response.ReusedCount
+
+	log.Printf("Bulk create: %d created, %d reused, %d failed", response.CreatedCount, response.ReusedCount, response.FailedCount)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// checks the items concurrently, returning the error of every item that failed, by index.
+// items that couldn't be checked, or not within bulkCheckTimeout, fail rather than the request.
+func checkBulkItems(r *http.Request, appEnv *middleware.AppEnv, items []bulkItem) ([]*BulkItemError, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), bulkCheckTimeout)
+	defer cancel()
+
+	itemErrors := make([]*BulkItemError, len(items))
+	errs := make([]error, len(items))
+	semaphore := make(chan struct{}, bulkCheckConcurrency)
+
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			itemErrors[i], errs[i] = checkBulkItem(ctx, appEnv, r.Host, item)
+			if errs[i] == nil || r.Context().Err() != nil {
+				return
+			}
+			// a failing check only fails its own item, like an invalid URL.
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				itemErrors[i], errs[i] = &BulkItemError{Error: "Gateway Timeout", Message: "The URL couldn't be checked in time."}, nil
+				return
+			}
+			log.Printf("Error checking bulk item %d: %s", i, errs[i].Error())
+			itemErrors[i], errs[i] = &BulkItemError{Error: "Internal Server Error", Message: "The URL couldn't be checked."}, nil
+		}()
+	}
+	wg.Wait()
+
+	return itemErrors, errors.Join(errs...)
+}
+
+// returns the error of an item that failed parsing, validation or the URL policy, or nil if it is valid.
+func checkBulkItem(ctx context.Context, appEnv *middleware.AppEnv, requestHost string, item bulkItem) (*BulkItemError, error) {
+	if item.parseError != "" {
+		return &BulkItemError{Error: "Bad Request", Message: item.parseError}, nil
+	}
+
+	if messages := middleware.ValidateStruct(item.request); messages != nil {
+		return &BulkItemError{Error: "ValidationError", Messages: messages}, nil
+	}
+
+	violation, err := appEnv.UrlPolicy.Check(ctx, item.request.OriginalUrl, requestHost)
+	if err != nil {
+		return nil, err
+	}
+	if violation != nil {
+		return &BulkItemError{Error: "Unprocessable Entity", Message: violation.Message, Rule: violation.Rule}, nil
+	}
+	return nil, nil
+}
+
+// finds existing aliases of the pending items and creates the missing ones. returns the
+// aliases by dedup key, and the keys of the aliases that were created.
+func resolveBulkAliases(r *http.Request, appEnv *middleware.AppEnv, tenant *dao.Tenant, ownerID string, pending []pendingBulkItem) (map[string]*dao.UrlAlias, map[string]bool, error) {
+	aliasesByKey := make(map[string]*dao.UrlAlias)
+	createdKeys := make(map[string]bool)
+
+	if len(pending) == 0 {
+		return aliasesByKey, createdKeys, nil
+	}
+
+	normalizedUrls := make([]string, 0, len(pending))
+	seenUrls := make(map[string]bool)
+	for _, item := range pending {
+		if !seenUrls[item.normalizedUrl] {
+			seenUrls[item.normalizedUrl] = true
+			normalizedUrls = append(normalizedUrls, item.normalizedUrl)
+		}
+	}
+
+	existing, err := appEnv.UrlAliasDao.FindByNormalizedUrls(r.Context(), tenant.ID, ownerID, normalizedUrls)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range existing {
+		urlAlias := &existing[i]
+		aliasesByKey[bulkDedupKey(urlAlias.NormalizedURL, urlAlias.RedirectCode, urlAlias.ExpiresAt)] = urlAlias
+	}
+
+	// one alias is created per distinct key.
+	toCreate := make(map[string]pendingBulkItem)
+	for _, item := range pending {
+		if _, found := aliasesByKey[item.key]; !found {
+			toCreate[item.key] = item
+		}
+	}
+
+	// generated aliases can collide with existing ones, in which case they are skipped by
+	// the insert and regenerated. shards that failed are retried the same way.
+	for attempt := 0; attempt < maxBulkInsertAttempts && len(toCreate) > 0; attempt++ {
+		keysByAlias := make(map[string]string, len(toCreate))
+		newAliases := make([]dao.UrlAlias, 0, len(toCreate))
+
+		for key, item := range toCreate {
+			alias, err := core.GenerateAlias(item.request.OriginalUrl, appEnv.AliasingStrategy, appEnv.ReservedAliases)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, taken := keysByAlias[alias]; taken {
+				continue
+			}
+
+			keysByAlias[alias] = key
+			newAliases = append(newAliases, dao.UrlAlias{
+				TenantID:      tenant.ID,
+				Alias:         alias,
+				OriginalURL:   item.request.OriginalUrl,
+				NormalizedURL: item.normalizedUrl,
+				RedirectCode:  item.redirectCode,
+				OwnerID:       ownerID,
+				ExpiresAt:     item.request.ExpiresAt,
+			})
+		}
+
+		created, err := appEnv.UrlAliasDao.CreateUrlAliases(r.Context(), newAliases)
+		if err != nil {
+			log.Printf("Error saving bulk aliases on attempt %d : %s", attempt+1, err)
+		}
+
+		for i := range created {
+			urlAlias := &created[i]
+			key := keysByAlias[urlAlias.Alias]
+			aliasesByKey[key] = urlAlias
+			createdKeys[key] = true
+			delete(toCreate, key)
+		}
+	}
+
+	return aliasesByKey, createdKeys, nil
+}
+
+// identifies the alias an item can share with other items or existing aliases.
+// expiry instants are compared at the precision stored by the database.
+func bulkDedupKey(normalizedUrl string, redirectCode int, expiresAt *time.Time) string {
+	var expiry string
+	if expiresAt != nil {
+		expiry = expiresAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%d|%s|%s", redirectCode, expiry, normalizedUrl)
+}
+
+// parses the items of a JSON or CSV bulk request. on failure it returns the status code to respond with.
+func parseBulkItems(r *http.Request) ([]bulkItem, int, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "application/json"
+	}
+
+	switch mediaType {
+	case "application/json":
+		var requests []CreateUrlAliasRequest
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return nil, bulkBodyErrorStatus(err), fmt.Errorf("The body must be a JSON array of create requests.")
+		}
+
+		items := make([]bulkItem, len(requests))
+		for i, request := range requests {
+			items[i] = bulkItem{request: request}
+		}
+		return items, http.StatusOK, nil
+
+	case "text/csv":
+		return parseBulkCSV(r.Body)
+
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, bulkBodyErrorStatus(err), fmt.Errorf("The form must contain a CSV 'file'.")
+		}
+		defer file.Close()
+		return parseBulkCSV(file)
+
+	default:
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("Use application/json, text/csv or multipart/form-data.")
+	}
+}
+
+// parses a CSV with a header row naming its columns. originalUrl is required,
+// redirectCode and expiresAt (RFC3339) are optional.
+func parseBulkCSV(body io.Reader) ([]bulkItem, int, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, bulkBodyErrorStatus(err), fmt.Errorf("The CSV must start with a header row.")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, found := columns["originalUrl"]; !found {
+		return nil, http.StatusBadRequest, fmt.Errorf("The CSV header must contain an 'originalUrl' column.")
+	}
+
+	column := func(record []string, name string) string {
+		if i, found := columns[name]; found && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var items []bulkItem
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, http.StatusBadRequest, fmt.Errorf("Malformed CSV: %s", parseErr.Error())
+			}
+			return nil, bulkBodyErrorStatus(err), fmt.Errorf("The CSV couldn't be read.")
+		}
+
+		item := bulkItem{request: CreateUrlAliasRequest{OriginalUrl: column(record, "originalUrl")}}
+
+		if redirectCode := column(record, "redirectCode"); redirectCode != "" {
+			if item.request.RedirectCode, err = strconv.Atoi(redirectCode); err != nil {
+				item.parseError = "'redirectCode' must be a number."
+			}
+		}
+
+		if expiresAt := column(record, "expiresAt"); expiresAt != "" && item.parseError == "" {
+			parsed, err := time.Parse(time.RFC3339, expiresAt)
+			if err != nil {
+				item.parseError = "'expiresAt' must be an RFC3339 timestamp."
+			}
+			item.request.ExpiresAt = &parsed
+		}
+
+		items = append(items, item)
+	}
+	return items, http.StatusOK, nil
+}
+
+func bulkBodyErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
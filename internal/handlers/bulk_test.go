@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+	"github.com/shashwatrathod/url-shortner/internal/urlpolicy"
+)
+
+// fakeUrlAliasDao keeps aliases in memory. only the methods used by bulk creates are
+// implemented, the others panic through the nil interface.
+type fakeUrlAliasDao struct {
+	dao.UrlAliasDao
+	aliases map[string]dao.UrlAlias
+	// number of calls to CreateUrlAliases.
+	createCalls int
+}
+
+func newFakeUrlAliasDao(existing ...dao.UrlAlias) *fakeUrlAliasDao {
+	d := &fakeUrlAliasDao{aliases: make(map[string]dao.UrlAlias)}
+	for _, urlAlias := range existing {
+		d.aliases[dao.AliasKey(urlAlias.TenantID, urlAlias.Alias)] = urlAlias
+	}
+	return d
+}
+
+func (d *fakeUrlAliasDao) FindByNormalizedUrls(ctx context.Context, tenantID string, ownerID string, normalizedUrls []string) ([]dao.UrlAlias, error) {
+	var found []dao.UrlAlias
+	for _, urlAlias := range d.aliases {
+		if urlAlias.TenantID == tenantID && urlAlias.OwnerID == ownerID && slices.Contains(normalizedUrls, urlAlias.NormalizedURL) {
+			found = append(found, urlAlias)
+		}
+	}
+	return found, nil
+}
+
+// skips aliases that already exist, like the database does.
+func (d *fakeUrlAliasDao) CreateUrlAliases(ctx context.Context, urlAliases []dao.UrlAlias) ([]dao.UrlAlias, error) {
+	d.createCalls++
+
+	var created []dao.UrlAlias
+	for _, urlAlias := range urlAliases {
+		key := dao.AliasKey(urlAlias.TenantID, urlAlias.Alias)
+		if _, found := d.aliases[key]; found {
+			continue
+		}
+		urlAlias.CreatedAt = time.Now()
+		d.aliases[key] = urlAlias
+		created = append(created, urlAlias)
+	}
+	return created, nil
+}
+
+// sequenceAliasingStrategy returns the aliases in order, repeating the last one.
+type sequenceAliasingStrategy struct {
+	aliases []string
+}
+
+func (s *sequenceAliasingStrategy) Alias(str string, length int) string {
+	alias := s.aliases[0]
+	if len(s.aliases) > 1 {
+		s.aliases = s.aliases[1:]
+	}
+	return alias
+}
+
+// failingRule fails to check URLs of the host error.example.
+type failingRule struct{}
+
+func (failingRule) Check(ctx context.Context, target *urlpolicy.Target) (*urlpolicy.Violation, error) {
+	if target.Host == "error.example" {
+		return nil, errors.New("lookup failed")
+	}
+	return nil, nil
+}
+
+func newBulkTestAppEnv(urlAliasDao dao.UrlAliasDao, aliases ...string) *middleware.AppEnv {
+	return &middleware.AppEnv{
+		Config: &config.Config{AliasConfig: config.AliasConfig{
+			DefaultRedirectCode: 302,
+			PublicBaseURL:       "https://sho.rt",
+			BulkCreateMaxItems:  10,
+		}},
+		UrlAliasDao:      urlAliasDao,
+		AliasingStrategy: &sequenceAliasingStrategy{aliases: aliases},
+		ReservedAliases:  core.NewReservedAliases(nil),
+		UrlNormalizer:    &core.UrlNormalizer{},
+		UrlPolicy:        urlpolicy.NewPolicy(urlpolicy.NewSchemeRule([]string{"http", "https"}), failingRule{}),
+	}
+}
+
+// sends the body to the bulk create handler and returns the recorded response.
+func serveBulkCreate(appEnv *middleware.AppEnv, contentType string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/api/create/bulk", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.ContextAppEnvKey, appEnv))
+
+	w := httptest.NewRecorder()
+	CreateUrlAliasesBulkHandler(w, r)
+	return w
+}
+
+func decodeBulkResponse(t *testing.T, w *httptest.ResponseRecorder) *BulkCreateResponse {
+	t.Helper()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("the handler responded with %d: %s", w.Code, w.Body.String())
+	}
+	var response BulkCreateResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return &response
+}
+
+func TestParseBulkItems(t *testing.T) {
+	var form bytes.Buffer
+	formWriter := multipart.NewWriter(&form)
+	file, err := formWriter.CreateFormFile("file", "urls.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("originalUrl\nhttps://example.com/a\n"))
+	formWriter.Close()
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []bulkItem
+		wantStatus  int
+	}{
+		{"JSON", "application/json", `[{"originalUrl":"https://example.com/a","redirectCode":301},{"originalUrl":"https://example.com/b"}]`,
+			[]bulkItem{{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/a", RedirectCode: 301}}, {request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/b"}}}, http.StatusOK},
+		{"JSON without a content type", "", `[{"originalUrl":"https://example.com/a"}]`,
+			[]bulkItem{{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/a"}}}, http.StatusOK},
+		{"CSV", "text/csv; charset=utf-8", "expiresAt, originalUrl, redirectCode\n2030-01-01T00:00:00Z, https://example.com/a, 308\n,https://example.com/b,abc\nsoon,https://example.com/c,\n,https://example.com/d\n",
+			[]bulkItem{
+				{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/a", RedirectCode: 308, ExpiresAt: &expiresAt}},
+				{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/b"}, parseError: "'redirectCode' must be a number."},
+				{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/c", ExpiresAt: &time.Time{}}, parseError: "'expiresAt' must be an RFC3339 timestamp."},
+				{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/d"}},
+			}, http.StatusOK},
+		{"multipart", formWriter.FormDataContentType(), form.String(),
+			[]bulkItem{{request: CreateUrlAliasRequest{OriginalUrl: "https://example.com/a"}}}, http.StatusOK},
+		{"malformed JSON", "application/json", `{"originalUrl":"https://example.com/a"}`, nil, http.StatusBadRequest},
+		{"CSV without an originalUrl column", "text/csv", "url\nhttps://example.com/a\n", nil, http.StatusBadRequest},
+		{"malformed CSV", "text/csv", "originalUrl\nhttps://example.com/\"a\n", nil, http.StatusBadRequest},
+		{"multipart without a file", "multipart/form-data; boundary=x", "--x--\r\n", nil, http.StatusBadRequest},
+		{"unsupported content type", "application/xml", "<urls/>", nil, http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/create/bulk", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", test.contentType)
+
+			items, status, err := parseBulkItems(r)
+			if status != test.wantStatus {
+				t.Fatalf("parseBulkItems responded with %d (%v), want %d", status, err, test.wantStatus)
+			}
+			if test.wantStatus != http.StatusOK {
+				if err == nil {
+					t.Errorf("parseBulkItems returned no error with status %d", status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBulkItems failed: %v", err)
+			}
+			if !reflect.DeepEqual(items, test.want) {
+				t.Errorf("parseBulkItems returned %+v, want %+v", items, test.want)
+			}
+		})
+	}
+}
+
+func TestParseBulkItemsTooLarge(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/api/create/bulk", strings.NewReader(`[{"originalUrl":"https://example.com/a"}]`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Body = http.MaxBytesReader(w, r.Body, 10)
+
+	if _, status, _ := parseBulkItems(r); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("parseBulkItems responded with %d, want %d", status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestCreateUrlAliasesBulkLimitsItems(t *testing.T) {
+	appEnv := newBulkTestAppEnv(newFakeUrlAliasDao(), "aaaaaaaa")
+	appEnv.Config.AliasConfig.BulkCreateMaxItems = 2
+
+	w := serveBulkCreate(appEnv, "text/csv", "originalUrl\nhttps://example.com/a\nhttps://example.com/b\nhttps://example.com/c\n")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("3 items with a limit of 2 got a %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	w = serveBulkCreate(appEnv, "application/json", "[]")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("an empty request got a %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCreateUrlAliasesBulk(t *testing.T) {
+	urlAliasDao := newFakeUrlAliasDao(dao.UrlAlias{
+		TenantID:      dao.DefaultTenantID,
+		Alias:         "existing",
+		OriginalURL:   "https://example.com/c",
+		NormalizedURL: "https://example.com/c",
+		RedirectCode:  302,
+	})
+	appEnv := newBulkTestAppEnv(urlAliasDao, "alias001", "alias002")
+
+	w := serveBulkCreate(appEnv, "application/json", `[
+		{"originalUrl":"https://example.com/a"},
+		{"originalUrl":"HTTPS://Example.com:443/a"},
+		{"originalUrl":"https://example.com/a","redirectCode":301},
+		{"originalUrl":"https://example.com/c/"},
+		{"originalUrl":"not a url"},
+		{"originalUrl":"ftp://example.com/a"},
+		{"originalUrl":"https://error.example/a"}
+	]`)
+	response := decodeBulkResponse(t, w)
+
+	if response.CreatedCount != 2 || response.ReusedCount != 2 || response.FailedCount != 3 {
+		t.Errorf("created %d, reused %d and failed %d, want 2, 2 and 3", response.CreatedCount, response.ReusedCount, response.FailedCount)
+	}
+	if len(response.Results) != 7 {
+		t.Fatalf("got %d results, want 7", len(response.Results))
+	}
+	for i, result := range response.Results {
+		if result.Index != i {
+			t.Errorf("result %d has the index %d", i, result.Index)
+		}
+	}
+
+	// items normalizing to the same URL and redirect code share one new alias.
+	first, second, withCode := response.Results[0].Result, response.Results[1].Result, response.Results[2].Result
+	if first == nil || second == nil || withCode == nil {
+		t.Fatalf("the first items failed: %+v", response.Results[:3])
+	}
+	if !first.Created || second.Created || first.Alias != second.Alias {
+		t.Errorf("the repeated URL got %+v and %+v, want the alias created by the first item", first, second)
+	}
+	if withCode.Alias == first.Alias || !withCode.Created || withCode.RedirectCode != 301 {
+		t.Errorf("the URL with another redirect code got %+v, want an alias of its own", withCode)
+	}
+	if first.ShortUrl != "https://sho.rt/"+first.Alias {
+		t.Errorf("the short URL is %s, want it on PUBLIC_BASE_URL", first.ShortUrl)
+	}
+
+	if existing := response.Results[3].Result; existing == nil || existing.Alias != "existing" || existing.Created {
+		t.Errorf("the existing URL got %+v, want the existing alias", response.Results[3])
+	}
+
+	for i, want := range []struct{ error, rule string }{{"ValidationError", ""}, {"Unprocessable Entity", "scheme"}, {"Internal Server Error", ""}} {
+		itemError := response.Results[4+i].Error
+		if itemError == nil || itemError.Error != want.error || itemError.Rule != want.rule {
+			t.Errorf("item %d failed with %+v, want %s %s", 4+i, itemError, want.error, want.rule)
+		}
+	}
+
+	if len(urlAliasDao.aliases) != 3 || urlAliasDao.createCalls != 1 {
+		t.Errorf("%d aliases after %d inserts, want 3 after 1", len(urlAliasDao.aliases), urlAliasDao.createCalls)
+	}
+}
+
+func TestCreateUrlAliasesBulkRetriesCollisions(t *testing.T) {
+	taken := dao.UrlAlias{TenantID: dao.DefaultTenantID, Alias: "taken001", OriginalURL: "https://example.com/other", NormalizedURL: "https://example.com/other", OwnerID: "someone"}
+
+	urlAliasDao := newFakeUrlAliasDao(taken)
+	appEnv := newBulkTestAppEnv(urlAliasDao, "taken001", "fresh001")
+
+	response := decodeBulkResponse(t, serveBulkCreate(appEnv, "application/json", `[{"originalUrl":"https://example.com/a"}]`))
+	if result := response.Results[0].Result; result == nil || result.Alias != "fresh001" || !result.Created {
+		t.Errorf("the item got %+v, want the regenerated alias", response.Results[0])
+	}
+	if urlAliasDao.createCalls != 2 {
+		t.Errorf("the aliases were inserted %d times, want 2", urlAliasDao.createCalls)
+	}
+
+	// items whose aliases keep colliding fail once the attempts are used up.
+	urlAliasDao = newFakeUrlAliasDao(taken)
+	appEnv = newBulkTestAppEnv(urlAliasDao, "taken001")
+
+	response = decodeBulkResponse(t, serveBulkCreate(appEnv, "application/json", `[{"originalUrl":"https://example.com/a"}]`))
+	if itemError := response.Results[0].Error; itemError == nil || response.FailedCount != 1 {
+		t.Errorf("the item got %+v, want it to fail", response.Results[0])
+	}
+	if urlAliasDao.createCalls != maxBulkInsertAttempts {
+		t.Errorf("the aliases were inserted %d times, want %d", urlAliasDao.createCalls, maxBulkInsertAttempts)
+	}
+}
@@ -20,7 +20,7 @@ type ErrorResponse struct {
 // @Tags errors
 // @Produce json
 // @Success 404 {object} ErrorResponse "Resource not found"
-// @Router /anyNonExistentRoute [get]
+// @Router /api/anyNonExistentRoute [get]
 func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	// Set the response status to 404 Not Found
 	w.Header().Set("Content-Type", "application/json")
@@ -43,7 +43,7 @@ func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 // @Tags errors
 // @Produce json
 // @Success 405 {object} ErrorResponse "Method not allowed"
-// @Router /anyRouteWithWrongMethod [put]
+// @Router /api/anyRouteWithWrongMethod [put]
 func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
 	// Set the response status to 405 Method Not Allowed
 	w.Header().Set("Content-Type", "application/json")
@@ -60,11 +60,11 @@ func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func SendErrorResponse(w http.ResponseWriter, errRes ErrorResponse, statusCode int) {
-	// Set the response status code
+	// Set the content type before the status code, headers written afterwards are ignored
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
 	// Encode the error response as JSON and write it to the response writer
-	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(errRes); err != nil {
 		http.Error(w, `{"error": "Internal Server Error", "message": "Failed to encode error response."}`, http.StatusInternalServerError)
 	}
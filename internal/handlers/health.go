@@ -18,7 +18,7 @@ type HealthResponse struct {
 // @Tags health
 // @Produce json
 // @Success 200 {object} HealthResponse "Application is healthy"
-// @Router /health [get]
+// @Router /api/health [get]
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	// Create a response object
 	response := HealthResponse{
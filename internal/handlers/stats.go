@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+)
+
+const (
+	// range used when the request doesn't specify one.
+	defaultStatsRange = 7 * 24 * time.Hour
+	// number of top referrers / user agents / countries returned by default.
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+	// longest ranges accepted per granularity, to bound the size of the time series.
+	maxHourlyStatsRange = 31 * 24 * time.Hour
+	maxDailyStatsRange  = 731 * 24 * time.Hour
+)
+
+// TimeSeriesPoint is the number of clicks within a single bucket.
+//
+// @Description Clicks within a single hour or day.
+type TimeSeriesPoint struct {
+	Bucket         time.Time `json:"bucket" example:"2025-06-01T00:00:00Z"`
+	Clicks         int64     `json:"clicks" example:"42"`
+	UniqueVisitors int64     `json:"uniqueVisitors" example:"30"`
+}
+
+// TopValue is the number of clicks for one referrer, user agent or country.
+//
+// @Description Number of clicks for a single referrer, user agent or country.
+type TopValue struct {
+	Value  string `json:"value" example:"https://news.ycombinator.com/"`
+	Clicks int64  `json:"clicks" example:"17"`
+}
+
+// RealtimeStats holds near-real-time click numbers served from the click counters.
+//
+// @Description Near-real-time click numbers, updated on every redirect.
+type RealtimeStats struct {
+	CurrentHourClicks         int64  `json:"currentHourClicks" example:"12"`
+	CurrentHourUniqueVisitors int64  `json:"currentHourUniqueVisitors" example:"9"`
+	Last24HoursClicks         int64  `json:"last24HoursClicks" example:"321"`
+	Last24HoursUniqueVisitors int64  `json:"last24HoursUniqueVisitors" example:"250"`
+	Source                    string `json:"source" example:"cache" enums:"cache,database"` // Where the numbers were read from
+}
+
+// AliasStatsResponse defines the response body of the alias statistics endpoint.
+//
+// @Description Click statistics of an alias over a time range.
+type AliasStatsResponse struct {
+	Alias          string            `json:"alias" example:"aBcDeFg1"`
+	From           time.Time         `json:"from" example:"2025-06-01T00:00:00Z"`
+	To             time.Time         `json:"to" example:"2025-06-08T00:00:00Z"`
+	Granularity    string            `json:"granularity" example:"day"`
+	TotalClicks    int64             `json:"totalClicks" example:"1234"`
+	UniqueVisitors int64             `json:"uniqueVisitors" example:"987"`
+	TimeSeries     []TimeSeriesPoint `json:"timeSeries"`
+	TopReferrers   []TopValue        `json:"topReferrers"`
+	TopUserAgents  []TopValue        `json:"topUserAgents"`
+	TopCountries   []TopValue        `json:"topCountries"`
+	RolledUpUntil  time.Time         `json:"rolledUpUntil" example:"2025-06-07T23:59:00Z"` // Clicks after this instant are not reflected yet, except in `realtime`
+	Realtime       *RealtimeStats    `json:"realtime"`
+}
+
+// statsQuery holds the parsed query parameters of a stats request.
+type statsQuery struct {
+	from        time.Time
+	to          time.Time
+	granularity string
+	limit       int
+}
+
+// GetAliasStatsHandler returns click statistics of an alias.
+//
+// Aggregates are read from the rollup tables on the alias's own shard, so clicks
+// recorded after `rolledUpUntil` are not reflected in them yet. The `realtime`
+// section is read from the click counters and is always up to date.
+//
+// @Summary Get alias statistics
+// @Description Returns total clicks, unique visitors, a click time series, the top referrers, user agents and countries, and near-real-time counters of an alias.
+// @Tags stats
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param alias path string true "URL Alias" example:"aBcDeFg1"
+// @Param from query string false "Start of the range (RFC3339). Defaults to 7 days before `to`."
+// @Param to query string false "End of the range (RFC3339). Defaults to now."
+// @Param granularity query string false "Time series bucket size" Enums(hour, day) default(day)
+// @Param limit query int false "Number of top referrers, user agents and countries" default(10)
+// @Success 200 {object} AliasStatsResponse "Statistics of the alias"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the read:stats scope"
+// @Failure 404 {object} ErrorResponse "Alias not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/aliases/{alias}/stats [get]
+func GetAliasStatsHandler(w http.ResponseWriter, r *http.Request) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("GetAliasStatsHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "GetAliasStatsHandler: Error accessing AppEnv.")
+		return
+	}
+
+	alias := mux.Vars(r)["alias"]
+	tenant := middleware.TenantFromContext(r.Context())
+	// analytics are keyed by the tenant qualified alias.
+	aliasKey := dao.AliasKey(tenant.ID, alias)
+
+	query, err := parseStatsQuery(r)
+	if err != nil {
+		SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	existingAlias, err := appEnv.UrlAliasDao.FindByAlias(r.Context(), tenant.ID, alias)
+	if err != nil {
+		log.Printf("Error: %s", err.Error())
+		SendInternalServerError(w, "GetAliasStatsHandler: Unexpected error while processing request.")
+		return
+	}
+
+	if existingAlias == nil {
+		SendErrorResponse(w, ErrorResponse{Error: "Not Found", Message: "The requested alias was not found."}, http.StatusNotFound)
+		return
+	}
+
+	response, err := buildAliasStats(r, appEnv.AliasStatsDao, aliasKey, query)
+	if err != nil {
+		log.Printf("GetAliasStatsHandler: Unexpected error while aggregating stats : %s.", err)
+		SendInternalServerError(w, "GetAliasStatsHandler: Unexpected error while aggregating stats.")
+		return
+	}
+
+	response.Alias = alias
+
+	realtime, err := appEnv.ClickCounter.GetRealtimeStats(r.Context(), aliasKey, time.Now())
+	if err != nil {
+		// the rollup based numbers are still useful on their own.
+		log.Printf("GetAliasStatsHandler: Error reading real-time counters : %s.", err)
+	} else {
+		response.Realtime = &RealtimeStats{
+			CurrentHourClicks:         realtime.CurrentHourClicks,
+			CurrentHourUniqueVisitors: realtime.CurrentHourUniqueVisitors,
+			Last24HoursClicks:         realtime.Last24HoursClicks,
+			Last24HoursUniqueVisitors: realtime.Last24HoursUniqueVisitors,
+			Source:                    realtime.Source,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func buildAliasStats(r *http.Request, statsDao dao.AliasStatsDao, alias string, query *statsQuery) (*AliasStatsResponse, error) {
+	ctx := r.Context()
+
+	summary, err := statsDao.GetClickSummary(ctx, alias, query.from, query.to)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := statsDao.GetClickTimeSeries(ctx, alias, query.granularity, truncateToBucket(query.from, query.granularity), query.to)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AliasStatsResponse{
+		Alias:          alias,
+		From:           query.from,
+		To:             query.to,
+		Granularity:    query.granularity,
+		TotalClicks:    summary.TotalClicks,
+		UniqueVisitors: summary.UniqueVisitors,
+		TimeSeries:     fillTimeSeries(buckets, query),
+		RolledUpUntil:  summary.RolledUpUntil,
+	}
+
+	topValues := map[string]*[]TopValue{
+		dao.DimensionReferrer:  &response.TopReferrers,
+		dao.DimensionUserAgent: &response.TopUserAgents,
+		dao.DimensionCountry:   &response.TopCountries,
+	}
+
+	for dimension, target := range topValues {
+		counts, err := statsDao.GetTopValues(ctx, alias, dimension, query.from, query.to, query.limit)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]TopValue, len(counts))
+		for i, count := range counts {
+			values[i] = TopValue{Value: count.Value, Clicks: count.Clicks}
+		}
+		*target = values
+	}
+
+	return response, nil
+}
+
+// returns a point for every bucket in the range, using zero for buckets without clicks.
+func fillTimeSeries(buckets []dao.ClickBucket, query *statsQuery) []TimeSeriesPoint {
+	step := time.Hour
+	if query.granularity == dao.GranularityDay {
+		step = 24 * time.Hour
+	}
+
+	byBucket := make(map[time.Time]dao.ClickBucket, len(buckets))
+	for _, bucket := range buckets {
+		byBucket[bucket.Bucket.UTC()] = bucket
+	}
+
+	points := make([]TimeSeriesPoint, 0)
+	for t := truncateToBucket(query.from, query.granularity); t.Before(query.to); t = t.Add(step) {
+		bucket := byBucket[t]
+		points = append(points, TimeSeriesPoint{
+			Bucket:         t,
+			Clicks:         bucket.Clicks,
+			UniqueVisitors: bucket.UniqueVisitors,
+		})
+	}
+	return points
+}
+
+func truncateToBucket(t time.Time, granularity string) time.Time {
+	if granularity == dao.GranularityDay {
+		return t.Truncate(24 * time.Hour)
+	}
+	return t.Truncate(time.Hour)
+}
+
+func parseStatsQuery(r *http.Request) (*statsQuery, error) {
+	params := r.URL.Query()
+
+	query := &statsQuery{
+		to:          time.Now().UTC(),
+		granularity: dao.GranularityDay,
+		limit:       defaultTopLimit,
+	}
+
+	if to := params.Get("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("'to' must be an RFC3339 timestamp")
+		}
+		query.to = parsed.UTC()
+	}
+
+	query.from = query.to.Add(-defaultStatsRange)
+	if from := params.Get("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("'from' must be an RFC3339 timestamp")
+		}
+		query.from = parsed.UTC()
+	}
+
+	if !query.from.Before(query.to) {
+		return nil, fmt.Errorf("'from' must be before 'to'")
+	}
+
+	maxRange := maxDailyStatsRange
+	switch granularity := params.Get("granularity"); granularity {
+	case "", dao.GranularityDay:
+	case dao.GranularityHour:
+		query.granularity = dao.GranularityHour
+		maxRange = maxHourlyStatsRange
+	default:
+		return nil, fmt.Errorf("'granularity' must be one of: hour, day")
+	}
+
+	if query.to.Sub(query.from) > maxRange {
+		return nil, fmt.Errorf("the requested range is too long for %s granularity", query.granularity)
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 || parsed > maxTopLimit {
+			return nil, fmt.Errorf("'limit' must be between 1 and %d", maxTopLimit)
+		}
+		query.limit = parsed
+	}
+
+	return query, nil
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/cache"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+	"github.com/shashwatrathod/url-shortner/internal/transfer"
+)
+
+// maximum size of an import request body. larger imports can be split, or run from the CLI.
+const maxImportBodyBytes = 64 << 20
+
+// ImportRecordError is a record that couldn't be imported.
+//
+// @Description A record that couldn't be imported.
+type ImportRecordError struct {
+	Line    int    `json:"line" example:"12"` // Line of the record in the uploaded file, starting at 1
+	Message string `json:"message" example:"unsupported redirect code 200"`
+}
+
+// ImportAliasesResponse defines the response body of the import endpoint.
+//
+// @Description Outcome of an import.
+type ImportAliasesResponse struct {
+	Inserted int                 `json:"inserted" example:"120"`
+	Updated  int                 `json:"updated" example:"3"`  // Existing aliases that were overwritten
+	Skipped  int                 `json:"skipped" example:"10"` // Existing aliases that were kept, and repeated records
+	Failed   int                 `json:"failed" example:"1"`
+	Errors   []ImportRecordError `json:"errors,omitempty"` // Errors of the first 100 failed records
+}
+
+// ExportAliasesHandler streams the aliases of the tenant.
+//
+// Every shard is read in turn and the aliases are written as they are read, so the
+// export is never held in memory. A failure after the first alias can only be
+// reported by cutting the response short.
+//
+// @Summary Export aliases
+// @Description Exports the aliases of the tenant of the request host as NDJSON or CSV, optionally filtered by creation date. The output can be fed back to the import endpoint.
+// @Tags admin
+// @Produce application/x-ndjson
+// @Produce text/csv
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param format query string false "Output format" Enums(ndjson, csv) default(ndjson)
+// @Param createdFrom query string false "Only export aliases created at or after this instant (RFC3339)"
+// @Param createdTo query string false "Only export aliases created before this instant (RFC3339)"
+// @Success 200 {string} string "The exported aliases"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the admin scope"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/aliases/export [get]
+func ExportAliasesHandler(w http.ResponseWriter, r *http.Request) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("ExportAliasesHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "ExportAliasesHandler: Error accessing AppEnv.")
+		return
+	}
+
+	format := transfer.FormatNDJSON
+	if name := r.URL.Query().Get("format"); name != "" {
+		parsed, err := transfer.ParseFormat(name)
+		if err != nil {
+			SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		format = parsed
+	}
+
+	filter := dao.UrlAliasExportFilter{TenantID: middleware.TenantFromContext(r.Context()).ID}
+	for param, target := range map[string]**time.Time{"createdFrom": &filter.CreatedFrom, "createdTo": &filter.CreatedTo} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: fmt.Sprintf("'%s' must be an RFC3339 timestamp.", param)}, http.StatusBadRequest)
+			return
+		}
+		*target = &parsed
+	}
+
+	writer, err := transfer.NewRecordWriter(w, format)
+	if err != nil {
+		SendInternalServerError(w, "ExportAliasesHandler: Unexpected error while creating the writer.")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"aliases.%s\"", format))
+
+	count, err := transfer.Export(r.Context(), appEnv.UrlAliasDao, writer, filter)
+	if err != nil {
+		// the status has been sent with the first alias, the truncated body is all that's left to signal the failure.
+		log.Printf("ExportAliasesHandler: Export failed after %d aliases : %s.", count, err)
+		if count == 0 {
+			SendInternalServerError(w, "ExportAliasesHandler: Unexpected error while exporting aliases.")
+		}
+		return
+	}
+
+	log.Printf("Exported %d aliases of tenant %s", count, filter.TenantID)
+}
+
+// ImportAliasesHandler imports aliases into the tenant.
+//
+// Imports are idempotent, so a failed import can be sent again as a whole.
+//
+// @Summary Import aliases
+// @Description Imports aliases exported by the export endpoint into the tenant of the request host. Records of other tenants are rejected. Existing aliases are skipped, overwritten, or abort the import depending on `conflict`. Invalid records are reported without failing the import.
+// @Tags admin
+// @Accept application/x-ndjson
+// @Accept text/csv
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param conflict query string false "Handling of aliases that already exist" Enums(skip, overwrite, fail) default(skip)
+// @Param aliases body string true "Aliases as NDJSON or CSV"
+// @Success 200 {object} ImportAliasesResponse "Outcome of the import"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters or malformed file"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the admin scope"
+// @Failure 409 {object} ErrorResponse "An alias already exists with different values and the conflict policy is fail"
+// @Failure 415 {object} ErrorResponse "Unsupported content type"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/aliases/import [post]
+func ImportAliasesHandler(w http.ResponseWriter, r *http.Request) {
+	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
+
+	if !ok || appEnv == nil {
+		log.Printf("ImportAliasesHandler: Error accessing AppEnv.")
+		SendInternalServerError(w, "ImportAliasesHandler: Error accessing AppEnv.")
+		return
+	}
+
+	policy := dao.ImportConflictSkip
+	if value := r.URL.Query().Get("conflict"); value != "" {
+		policy = dao.ImportConflictPolicy(value)
+		if !transfer.IsKnownPolicy(policy) {
+			SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: "'conflict' must be one of skip, overwrite or fail."}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := transfer.FormatNDJSON
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		switch mediaType {
+		case "text/csv":
+			format = transfer.FormatCSV
+		case "application/x-ndjson", "application/jsonl", "application/json":
+		default:
+			SendErrorResponse(w, ErrorResponse{Error: "Unsupported Media Type", Message: "Use application/x-ndjson or text/csv."}, http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+
+	reader, err := transfer.NewRecordReader(r.Body, format)
+	if err != nil {
+		SendInternalServerError(w, "ImportAliasesHandler: Unexpected error while creating the reader.")
+		return
+	}
+
+	tenant := middleware.TenantFromContext(r.Context())
+	result, err := transfer.Import(r.Context(), appEnv.UrlAliasDao, reader, transfer.ImportOptions{
+		Policy:              policy,
+		TenantID:            tenant.ID,
+		Normalizer:          appEnv.UrlNormalizer,
+		ReservedAliases:     appEnv.ReservedAliases,
+		UrlPolicy:           appEnv.UrlPolicy,
+		RequestHost:         r.Host,
+		DefaultRedirectCode: appEnv.Config.AliasConfig.DefaultRedirectCode,
+	})
+
+	if result != nil && len(result.UpdatedKeys) > 0 {
+		// overwritten aliases would keep redirecting to their old destination until their cache entry expires.
+		if err := cache.DeleteAliasRecords(r.Context(), appEnv.CacheManager, result.UpdatedKeys...); err != nil {
+			log.Printf("ImportAliasesHandler: Error evicting overwritten aliases from the cache : %s.", err)
+		}
+	}
+
+	if err != nil {
+		var conflictErr *dao.UrlAliasConflictError
+		var inputErr *transfer.InputError
+		switch {
+		case errors.As(err, &conflictErr):
+			SendErrorResponse(w, ErrorResponse{Error: "Conflict", Message: fmt.Sprintf("The alias '%s' already exists with different values.", conflictErr.Alias)}, http.StatusConflict)
+		case errors.As(err, &inputErr):
+			status := bulkBodyErrorStatus(err)
+			SendErrorResponse(w, ErrorResponse{Error: http.StatusText(status), Message: err.Error()}, status)
+		default:
+			log.Printf("ImportAliasesHandler: Unexpected error while importing aliases : %s.", err)
+			SendInternalServerError(w, "ImportAliasesHandler: Unexpected error while importing aliases.")
+		}
+		return
+	}
+
+	response := &ImportAliasesResponse{
+		Inserted: result.Inserted,
+		Updated:  result.Updated,
+		Skipped:  result.Skipped,
+		Failed:   result.Failed,
+	}
+	for _, recordErr := range result.Errors {
+		response.Errors = append(response.Errors, ImportRecordError{Line: recordErr.Line, Message: recordErr.Message})
+	}
+
+	log.Printf("Imported aliases into tenant %s: %d inserted, %d updated, %d skipped, %d failed",
+		tenant.ID, result.Inserted, result.Updated, result.Skipped, result.Failed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -3,50 +3,80 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/shashwatrathod/url-shortner/internal/analytics"
 	"github.com/shashwatrathod/url-shortner/internal/cache"
 	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
 	"github.com/shashwatrathod/url-shortner/internal/middleware"
 )
 
-const ALIAS_CACHE_STORE = "aliases"
-
 // CreateUrlAliasRequest defines the request body for creating a URL alias.
 //
 // @Description Request body for creating a URL alias.
 type CreateUrlAliasRequest struct {
-	OriginalUrl string `json:"originalUrl" validate:"required,url" example:"https://example.com/very/long/url/to/shorten"`
+	OriginalUrl  string     `json:"originalUrl" validate:"required,url" example:"https://example.com/very/long/url/to/shorten"`
+	RedirectCode int        `json:"redirectCode,omitempty" validate:"omitempty,oneof=301 302 307 308" example:"301"` // HTTP status used for the redirect. Defaults to the server's configured code (302 unless overridden)
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty" validate:"omitempty,gt" example:"2025-12-31T23:59:59Z"`      // Instant after which the alias stops redirecting. Must be in the future. Never expires if omitted
 }
 
 // CreateUrlAliasResponse defines the response body for a created URL alias.
 //
 // @Description Response body for a created URL alias.
 type CreateUrlAliasResponse struct {
-	UrlAlias string `json:"urlAlias" example:"aBcDeFg1"`
+	ShortUrl     string     `json:"shortUrl" example:"https://sho.rt/aBcDeFg1"` // Fully-qualified short URL on the tenant's domain
+	Alias        string     `json:"alias" example:"aBcDeFg1"`
+	OriginalUrl  string     `json:"originalUrl" example:"https://example.com/very/long/url/to/shorten"`
+	RedirectCode int        `json:"redirectCode" example:"301"`
+	CreatedAt    time.Time  `json:"createdAt" example:"2025-06-30T09:00:00Z"`
+	ExpiresAt    *time.Time `json:"expiresAt" example:"2025-12-31T23:59:59Z"` // Null if the alias never expires
+	Created      bool       `json:"created" example:"true"`                   // False if an existing alias was reused
+	UrlAlias     string     `json:"urlAlias" example:"aBcDeFg1"`              // Deprecated: use alias
+}
+
+// UrlPolicyErrorResponse is the error response for destination URLs rejected by the URL policy.
+//
+// @Description Error response for a destination URL that isn't allowed.
+type UrlPolicyErrorResponse struct {
+	Error   string `json:"error" example:"Unprocessable Entity"`
+	Message string `json:"message" example:"Links to private or internal addresses aren't allowed."`
+	Field   string `json:"field" example:"originalUrl"`                                                                                                       // Request field holding the rejected value
+	Rule    string `json:"rule" example:"private_network" enums:"syntax,max_length,scheme,denied_host,allowed_host,self_reference,blocklist,private_network"` // Policy rule that was violated
 }
 
 // CreateUrlAliasHandler handles HTTP requests for creating a new URL alias
 // or retrieving an existing one for a given original URL.
 // It expects a CreateUrlRequest in the request body.
 //
-// On success, it responds with a JSON object containing the aliased URL.
+// On success, it responds with 201 Created and the new alias, or with 200 OK
+// and an existing alias of the caller for the same URL, redirect code and expiry.
 // If an error occurs during processing (e.g., issues with application environment,
 // database operations, or URL generation), it logs the error and responds with
 // an HTTP 500 Internal Server Error.
 //
 // @Summary Create or get a URL alias
-// @Description Creates a new URL alias for a given original URL or returns an existing one.
+// @Description Creates a new URL alias owned by the caller for a given original URL, or returns one the caller already owns.
 // @Tags urls
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param request body CreateUrlAliasRequest true "Request body to create a URL alias"
-// @Success 200 {object} CreateUrlAliasResponse "Successfully created or retrieved alias"
+// @Success 200 {object} CreateUrlAliasResponse "Reused an existing alias"
+// @Success 201 {object} CreateUrlAliasResponse "Created a new alias"
 // @Failure 400 {object} middleware.ValidationError "Invalid request payload (validation error)"
+// @Failure 401 {object} ErrorResponse "Missing or invalid credentials"
+// @Failure 403 {object} ErrorResponse "Credentials lack the create scope"
+// @Failure 422 {object} UrlPolicyErrorResponse "Destination URL not allowed by the URL policy"
+// @Failure 429 {object} ErrorResponse "Rate limit exceeded, see the Retry-After header"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /create [post]
+// @Router /api/create [post]
 func CreateUrlAliasHandler(w http.ResponseWriter, r *http.Request, req CreateUrlAliasRequest) {
 	appEnv, ok := r.Context().Value(middleware.ContextAppEnvKey).(*middleware.AppEnv)
 
@@ -57,7 +87,53 @@ func CreateUrlAliasHandler(w http.ResponseWriter, r *http.Request, req CreateUrl
 		return
 	}
 
-	existingAlias, err := appEnv.UrlAliasDao.FindByOriginalUrl(r.Context(), req.OriginalUrl)
+	violation, err := appEnv.UrlPolicy.Check(r.Context(), req.OriginalUrl, r.Host)
+	if err != nil {
+		log.Printf("CreateUrlAliasHandler: Unexpected error while checking the URL policy : %s.", err)
+		SendInternalServerError(w, "CreateUrlAliasHandler: Unexpected error while checking the URL policy.")
+		return
+	}
+
+	if violation != nil {
+		log.Printf("Rejected destination URL %s : %s", req.OriginalUrl, violation)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(&UrlPolicyErrorResponse{
+			Error:   "Unprocessable Entity",
+			Message: violation.Message,
+			Field:   "originalUrl",
+			Rule:    violation.Rule,
+		})
+		return
+	}
+
+	// equivalent URLs are looked up and stored in their canonical form, so that they share an alias.
+	normalizedUrl, err := appEnv.UrlNormalizer.Normalize(req.OriginalUrl)
+	if err != nil {
+		SendErrorResponse(w, ErrorResponse{Error: "Bad Request", Message: "The URL can't be parsed."}, http.StatusBadRequest)
+		return
+	}
+
+	redirectCode := req.RedirectCode
+	if redirectCode == 0 {
+		redirectCode = appEnv.Config.AliasConfig.DefaultRedirectCode
+	}
+
+	tenant := middleware.TenantFromContext(r.Context())
+
+	// aliases are owned by the caller, so only the caller's own aliases are reused.
+	var ownerID string
+	if identity := middleware.IdentityFromContext(r.Context()); identity != nil {
+		ownerID = identity.Subject
+	}
+
+	existingAlias, err := appEnv.UrlAliasDao.FindByOriginalUrl(r.Context(), dao.OriginalUrlLookup{
+		TenantID:      tenant.ID,
+		NormalizedUrl: normalizedUrl,
+		RedirectCode:  redirectCode,
+		OwnerID:       ownerID,
+		ExpiresAt:     req.ExpiresAt,
+	})
 
 	if err != nil {
 		log.Printf("Error: %s", err.Error())
@@ -67,15 +143,12 @@ func CreateUrlAliasHandler(w http.ResponseWriter, r *http.Request, req CreateUrl
 
 	if existingAlias != nil {
 		log.Printf("Found an existing alias for %s : %s", req.OriginalUrl, existingAlias.Alias)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(&CreateUrlAliasResponse{
-			UrlAlias: existingAlias.Alias,
-		})
+		sendCreateUrlAliasResponse(w, r, appEnv, tenant, existingAlias, http.StatusOK)
 		return
 	}
 
 	// no existing urls in db - create new short url.
-	shortUrl, err := core.GenerateAlias(req.OriginalUrl, appEnv.AliasingStrategy)
+	shortUrl, err := core.GenerateAlias(req.OriginalUrl, appEnv.AliasingStrategy, appEnv.ReservedAliases)
 
 	if err != nil {
 		log.Printf("CreateShortUrlHandler: Unexpected error while generating alias : %s.", err)
@@ -83,32 +156,65 @@ func CreateUrlAliasHandler(w http.ResponseWriter, r *http.Request, req CreateUrl
 		return
 	}
 
-	urlAlias, err := appEnv.UrlAliasDao.CreateUrlAlias(r.Context(), shortUrl, req.OriginalUrl)
+	urlAlias, err := appEnv.UrlAliasDao.CreateUrlAlias(r.Context(), &dao.UrlAlias{
+		TenantID:      tenant.ID,
+		Alias:         shortUrl,
+		OriginalURL:   req.OriginalUrl,
+		NormalizedURL: normalizedUrl,
+		RedirectCode:  redirectCode,
+		OwnerID:       ownerID,
+		ExpiresAt:     req.ExpiresAt,
+	})
 
 	if err != nil {
 		log.Printf("CreateShortUrlHandler: Unexpected error while saving alias : %s.", err)
 		SendInternalServerError(w, "CreateShortUrlHandler: Unexpected error while saving alias.")
 		return
 	}
+	sendCreateUrlAliasResponse(w, r, appEnv, tenant, urlAlias, http.StatusCreated)
+}
+
+// responds with the alias, using 201 Created for new aliases and 200 OK for reused ones.
+func sendCreateUrlAliasResponse(w http.ResponseWriter, r *http.Request, appEnv *middleware.AppEnv, tenant *dao.Tenant, urlAlias *dao.UrlAlias, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(&CreateUrlAliasResponse{
-		UrlAlias: urlAlias.Alias,
-	})
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(newCreateUrlAliasResponse(r, appEnv, tenant, urlAlias, statusCode == http.StatusCreated))
+}
+
+func newCreateUrlAliasResponse(r *http.Request, appEnv *middleware.AppEnv, tenant *dao.Tenant, urlAlias *dao.UrlAlias, created bool) *CreateUrlAliasResponse {
+	return &CreateUrlAliasResponse{
+		ShortUrl:     shortUrlFor(r, appEnv, tenant, urlAlias.Alias),
+		Alias:        urlAlias.Alias,
+		OriginalUrl:  urlAlias.OriginalURL,
+		RedirectCode: urlAlias.RedirectCode,
+		CreatedAt:    urlAlias.CreatedAt,
+		ExpiresAt:    urlAlias.ExpiresAt,
+		Created:      created,
+		UrlAlias:     urlAlias.Alias,
+	}
 }
 
 // GetUrlAliasHandler handles HTTP requests to retrieve and redirect to an original URL
 // based on a given alias.
 //
-// If the alias is found, it redirects the client to the original URL (HTTP 302).
-// If the alias is not found, it responds with an HTTP 404 Not Found.
+// If the alias is found, it redirects the client to the original URL using the
+// alias's redirect code (HTTP 301, 302, 307 or 308) and queues a click event for
+// asynchronous recording.
+// If the alias is not found, it responds with an HTTP 404 Not Found, and if it is
+// disabled or expired, with an HTTP 410 Gone.
 // If an internal error occurs, it responds with an HTTP 500 Internal Server Error.
 // @Summary Redirect to original URL
 // @Description Retrieves the original URL for a given alias and redirects to it.
 // @Tags urls
 // @Produce html
 // @Param alias path string true "URL Alias" example:"aBcDeFg1"
+// @Success 301 "Permanently redirects to the original URL (Location header will be set)"
 // @Success 302 "Redirects to the original URL (Location header will be set)"
+// @Success 307 "Temporarily redirects to the original URL, preserving the method and body"
+// @Success 308 "Permanently redirects to the original URL, preserving the method and body"
 // @Failure 404 {object} ErrorResponse "Alias not found"
+// @Failure 410 {object} ErrorResponse "Alias disabled or expired"
+// @Failure 429 {object} ErrorResponse "Rate limit exceeded, see the Retry-After header"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /{alias} [get]
 func GetUrlAliasHandler(w http.ResponseWriter, r *http.Request) {
@@ -122,21 +228,23 @@ func GetUrlAliasHandler(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	alias := vars["alias"]
+	tenant := middleware.TenantFromContext(r.Context())
+	aliasKey := dao.AliasKey(tenant.ID, alias)
 
 	// try to find the value from cache.
-	cachedOriginalUrl, err := appEnv.CacheManager.Get(r.Context(), ALIAS_CACHE_STORE, alias)
+	record, err := cache.GetAliasRecord(r.Context(), appEnv.CacheManager, aliasKey)
 	if err != nil {
 		log.Printf("Error while fetching cached content: %s", err.Error())
 	}
 
-	if cachedOriginalUrl != nil {
-		log.Printf("Cache hit for alias '%s'. Redirecting to: %s", alias, cachedOriginalUrl.(string))
-		http.Redirect(w, r, cachedOriginalUrl.(string), http.StatusFound)
+	if record != nil {
+		log.Printf("Cache hit for alias '%s'. Redirecting to: %s", aliasKey, record.URL)
+		redirectToAliasRecord(w, r, appEnv, aliasKey, record)
 		return
 	}
 
 	// fetch value from db
-	existingAlias, err := appEnv.UrlAliasDao.FindByAlias(r.Context(), alias)
+	existingAlias, err := appEnv.UrlAliasDao.FindByAlias(r.Context(), tenant.ID, alias)
 
 	if err != nil {
 		log.Printf("Error: %s", err.Error())
@@ -145,18 +253,23 @@ func GetUrlAliasHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if existingAlias != nil {
-		http.Redirect(w, r, existingAlias.OriginalURL, http.StatusFound)
+		record := &cache.AliasRecord{
+			URL:          existingAlias.OriginalURL,
+			RedirectCode: existingAlias.RedirectCode,
+			ExpiresAt:    existingAlias.ExpiresAt,
+		}
+		redirectToAliasRecord(w, r, appEnv, aliasKey, record)
 
 		// asyncrhonously save the fetched value to cache for future use.
-		go func(alias string, originalUrl string, cm cache.CacheManager) {
+		go func(alias string, record *cache.AliasRecord, cm cache.CacheManager, ttl time.Duration) {
 			bgCtx := context.Background()
-			err := appEnv.CacheManager.Set(bgCtx, ALIAS_CACHE_STORE, alias, originalUrl)
+			err := cache.SetAliasRecord(bgCtx, cm, alias, record, ttl)
 			if err != nil {
 				log.Printf("Error setting cache for alias '%s' after DB hit: %s", alias, err.Error())
 			} else {
 				log.Printf("Successfully cached alias '%s' after DB hit.", alias)
 			}
-		}(alias, existingAlias.OriginalURL, appEnv.CacheManager)
+		}(aliasKey, record, appEnv.CacheManager, appEnv.Config.AliasConfig.CacheTTL)
 
 		return
 	}
@@ -165,3 +278,74 @@ func GetUrlAliasHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 	json.NewEncoder(w).Encode(ErrorResponse{Error: "Not Found", Message: "The requested alias was not found."})
 }
+
+// DeprecatedGetUrlAliasHandler serves redirects on the old /api/{alias} route.
+// It behaves like GetUrlAliasHandler, and marks the response as deprecated in
+// favor of the root-level route.
+//
+// @Summary Redirect to original URL (deprecated)
+// @Description Deprecated: use /{alias}. Retrieves the original URL for a given alias and redirects to it.
+// @Tags urls
+// @Produce html
+// @Deprecated
+// @Param alias path string true "URL Alias" example:"aBcDeFg1"
+// @Success 301 "Permanently redirects to the original URL (Location header will be set)"
+// @Success 302 "Redirects to the original URL (Location header will be set)"
+// @Success 307 "Temporarily redirects to the original URL, preserving the method and body"
+// @Success 308 "Permanently redirects to the original URL, preserving the method and body"
+// @Failure 404 {object} ErrorResponse "Alias not found"
+// @Failure 410 {object} ErrorResponse "Alias disabled or expired"
+// @Failure 429 {object} ErrorResponse "Rate limit exceeded, see the Retry-After header"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/{alias} [get]
+func DeprecatedGetUrlAliasHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", fmt.Sprintf(`</%s>; rel="successor-version"`, url.PathEscape(mux.Vars(r)["alias"])))
+	GetUrlAliasHandler(w, r)
+}
+
+// redirects to the record's URL, or responds with 410 Gone if the alias
+// is disabled or has expired. aliasKey is the tenant qualified key of the alias.
+func redirectToAliasRecord(w http.ResponseWriter, r *http.Request, appEnv *middleware.AppEnv, aliasKey string, record *cache.AliasRecord) {
+	if record.Disabled {
+		SendErrorResponse(w, ErrorResponse{Error: "Gone", Message: "The requested alias has been disabled."}, http.StatusGone)
+		return
+	}
+
+	if record.IsExpired(time.Now()) {
+		SendErrorResponse(w, ErrorResponse{Error: "Gone", Message: "The requested alias has expired."}, http.StatusGone)
+		return
+	}
+
+	http.Redirect(w, r, record.URL, record.RedirectCode)
+	recordClick(appEnv, r, aliasKey)
+}
+
+// queues the click for persistence and for the real-time counters, so that neither
+// adds latency to the redirect.
+func recordClick(appEnv *middleware.AppEnv, r *http.Request, aliasKey string) {
+	event := analytics.NewClickEvent(r, aliasKey, appEnv.Config.ServerConfig.TrustedProxies.ClientIP(r))
+	appEnv.ClickRecorder.Record(event)
+	appEnv.ClickCounter.Record(event)
+}
+
+// returns the fully-qualified short URL of the alias. aliases of a tenant use the
+// tenant's domain, while aliases of the default tenant use the configured public
+// base URL, or the host of the request if there is none. tenant domains take the
+// scheme of the public base URL, or of the request if there is none.
+func shortUrlFor(r *http.Request, appEnv *middleware.AppEnv, tenant *dao.Tenant, alias string) string {
+	baseURL := appEnv.Config.AliasConfig.PublicBaseURL
+	scheme := appEnv.Config.ServerConfig.TrustedProxies.Scheme(r)
+
+	if tenant.Domain != "" {
+		if parsed, err := url.Parse(baseURL); baseURL != "" && err == nil && parsed.Scheme != "" {
+			scheme = parsed.Scheme
+		}
+		return scheme + "://" + tenant.Domain + "/" + alias
+	}
+
+	if baseURL != "" {
+		return baseURL + "/" + alias
+	}
+	return scheme + "://" + r.Host + "/" + alias
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// PeriodicJob runs a background task once every interval, e.g. aggregating
+// raw clicks into the rollup tables that back the statistics API, or re-reading
+// rotated secrets.
+type PeriodicJob struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+
+	// guards interval and ticker, which SetInterval changes while the job runs.
+	mu       sync.Mutex
+	interval time.Duration
+	ticker   *time.Ticker
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewPeriodicJob(name string, interval time.Duration, timeout time.Duration, run func(ctx context.Context) error) *PeriodicJob {
+	return &PeriodicJob{
+		name:     name,
+		interval: interval,
+		timeout:  timeout,
+		run:      run,
+		stop:     make(chan struct{}),
+	}
+}
+
+// runs the task immediately and then once every interval until Stop is called.
+func (j *PeriodicJob) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		j.mu.Lock()
+		ticker := time.NewTicker(j.interval)
+		j.ticker = ticker
+		j.mu.Unlock()
+
+		defer func() {
+			j.mu.Lock()
+			defer j.mu.Unlock()
+			ticker.Stop()
+			j.ticker = nil
+		}()
+
+		for {
+			j.runOnce()
+
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// changes the interval of the job, e.g. after the configuration was reloaded. the next run
+// is an interval after the change.
+func (j *PeriodicJob) SetInterval(interval time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if interval == j.interval {
+		return
+	}
+	j.interval = interval
+	if j.ticker != nil {
+		j.ticker.Reset(interval)
+	}
+	log.Printf("running %s every %s", j.name, interval)
+}
+
+// stops the job and waits for an in-flight run to finish.
+func (j *PeriodicJob) Stop() {
+	close(j.stop)
+	j.wg.Wait()
+}
+
+func (j *PeriodicJob) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+
+	if err := j.run(ctx); err != nil {
+		log.Printf("Error running %s: %s", j.name, err.Error())
+		return
+	}
+	log.Printf("successfully ran %s", j.name)
+}
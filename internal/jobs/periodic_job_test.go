@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPeriodicJobSetInterval(t *testing.T) {
+	runs := make(chan struct{}, 10)
+	job := NewPeriodicJob("test", time.Hour, time.Second, func(ctx context.Context) error {
+		runs <- struct{}{}
+		return nil
+	})
+	job.Start()
+	defer job.Stop()
+
+	// the first run starts right away, the next one would be an hour later.
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job didn't run on start")
+	}
+
+	job.SetInterval(10 * time.Millisecond)
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job didn't run with the shorter interval")
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/shashwatrathod/url-shortner/internal/auth"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+// header carrying the API key of the caller.
+const APIKeyHeader = "X-API-Key"
+
+// prefix of the Authorization header carrying a bearer token.
+const bearerPrefix = "Bearer "
+
+// ContextIdentityKey is the key used to store the authenticated auth.Identity in the context.
+const ContextIdentityKey contextKey = "identity"
+
+// key of the authResult stored by Authenticate.
+const contextAuthResultKey contextKey = "authResult"
+
+// outcome of authenticating a request. identity is nil if the caller couldn't be
+// authenticated, with status and message describing the response to send.
+type authResult struct {
+	identity *auth.Identity
+	status   int
+	message  string
+}
+
+// errorBody mirrors handlers.ErrorResponse, which can't be imported here without an import cycle.
+type errorBody struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func sendErrorBody(w http.ResponseWriter, statusCode int, errType string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(&errorBody{Error: errType, Message: message})
+}
+
+// returns the identity authenticated for the request, or nil if there is none.
+func IdentityFromContext(ctx context.Context) *auth.Identity {
+	identity, _ := ctx.Value(ContextIdentityKey).(*auth.Identity)
+	return identity
+}
+
+// Authenticate resolves the identity of the caller, from either a bearer token or an API key,
+// without rejecting the request. Middlewares running after it use the outcome: RateLimit
+// limits authenticated callers by their identity, and RequireScopes rejects the others.
+// Running RateLimit in between makes requests with invalid credentials count against the
+// limit of their client IP.
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appEnv, ok := r.Context().Value(ContextAppEnvKey).(*AppEnv)
+		if !ok || appEnv == nil {
+			log.Printf("Authenticate: Error accessing AppEnv.")
+			sendErrorBody(w, http.StatusInternalServerError, "Internal Server Error", "Authenticate: Error accessing AppEnv.")
+			return
+		}
+
+		identity, status, message := authenticate(r, appEnv)
+		ctx := context.WithValue(r.Context(), contextAuthResultKey, &authResult{identity: identity, status: status, message: message})
+		if identity != nil {
+			ctx = context.WithValue(ctx, ContextIdentityKey, identity)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScopes only lets the request through if the caller was authenticated and granted
+// every one of the scopes, authenticating it unless Authenticate already did.
+// It responds with 401 Unauthorized if the caller couldn't be authenticated,
+// and 403 Forbidden if a scope is missing or the caller belongs to another tenant.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			appEnv, ok := r.Context().Value(ContextAppEnvKey).(*AppEnv)
+			if !ok || appEnv == nil {
+				log.Printf("RequireScopes: Error accessing AppEnv.")
+				sendErrorBody(w, http.StatusInternalServerError, "Internal Server Error", "RequireScopes: Error accessing AppEnv.")
+				return
+			}
+
+			result, ok := r.Context().Value(contextAuthResultKey).(*authResult)
+			if !ok {
+				identity, status, message := authenticate(r, appEnv)
+				result = &authResult{identity: identity, status: status, message: message}
+			}
+
+			identity, status, message := result.identity, result.status, result.message
+			if identity == nil {
+				if status == http.StatusUnauthorized {
+					w.Header().Set("WWW-Authenticate", `Bearer, ApiKey header="`+APIKeyHeader+`"`)
+					sendErrorBody(w, status, "Unauthorized", message)
+				} else {
+					sendErrorBody(w, status, "Internal Server Error", message)
+				}
+				return
+			}
+
+			// credentials are only valid on the domain of their tenant.
+			if tenant := TenantFromContext(r.Context()); identity.TenantID != tenant.ID {
+				sendErrorBody(w, http.StatusForbidden, "Forbidden", "The credentials belong to a different tenant.")
+				return
+			}
+
+			for _, scope := range scopes {
+				if !identity.HasScope(scope) {
+					sendErrorBody(w, http.StatusForbidden, "Forbidden", "The credentials are missing the required scope '"+scope+"'.")
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), ContextIdentityKey, identity)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// resolves the identity of the caller. on failure it returns a nil identity
+// together with the status code and message to respond with.
+func authenticate(r *http.Request, appEnv *AppEnv) (*auth.Identity, int, string) {
+	if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, bearerPrefix) {
+		return authenticateBearerToken(r, appEnv, strings.TrimPrefix(authorization, bearerPrefix))
+	}
+
+	rawKey := r.Header.Get(APIKeyHeader)
+	if rawKey == "" {
+		return nil, http.StatusUnauthorized, "A bearer token or an API key in the " + APIKeyHeader + " header is required."
+	}
+
+	id, secret, err := auth.ParseAPIKey(rawKey)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "The API key is invalid."
+	}
+
+	apiKey, err := appEnv.ApiKeyDao.FindApiKeyByID(r.Context(), id)
+	if err != nil {
+		log.Printf("Error looking up API key '%s': %s", id, err.Error())
+		return nil, http.StatusInternalServerError, "Unexpected error while authenticating the request."
+	}
+
+	if apiKey == nil || apiKey.RevokedAt != nil || !auth.VerifyAPIKeySecret(secret, apiKey.KeyHash) {
+		return nil, http.StatusUnauthorized, "The API key is invalid."
+	}
+
+	return &auth.Identity{
+		Subject:  "apikey:" + apiKey.ID,
+		TenantID: apiKey.TenantID,
+		Method:   auth.MethodAPIKey,
+		Scopes:   apiKey.Scopes,
+	}, http.StatusOK, ""
+}
+
+func authenticateBearerToken(r *http.Request, appEnv *AppEnv, token string) (*auth.Identity, int, string) {
+	if appEnv.JWTVerifier == nil {
+		return nil, http.StatusUnauthorized, "Bearer tokens are not accepted by this server."
+	}
+
+	identity, err := appEnv.JWTVerifier.Verify(r.Context(), strings.TrimSpace(token))
+	if err != nil {
+		log.Printf("Rejected bearer token: %s", err.Error())
+		return nil, http.StatusUnauthorized, "The bearer token is invalid."
+	}
+
+	// tokens without the tenant claim are only valid on the default domain, not on every domain.
+	if identity.TenantID == "" {
+		identity.TenantID = dao.DefaultTenantID
+	}
+	return identity, http.StatusOK, ""
+}
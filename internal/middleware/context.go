@@ -3,39 +3,126 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"sync/atomic"
 
+	"github.com/shashwatrathod/url-shortner/internal/analytics"
+	"github.com/shashwatrathod/url-shortner/internal/auth"
 	"github.com/shashwatrathod/url-shortner/internal/cache"
+	"github.com/shashwatrathod/url-shortner/internal/config"
 	"github.com/shashwatrathod/url-shortner/internal/core"
 	"github.com/shashwatrathod/url-shortner/internal/db"
 	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/ratelimit"
+	"github.com/shashwatrathod/url-shortner/internal/urlpolicy"
 )
 
 type AppEnv struct {
-	DBManager        *db.ConnectionManager
-	UrlAliasDao      dao.UrlAliasDao
+	Config         *config.Config
+	DBManager      *db.ConnectionManager
+	UrlAliasDao    dao.UrlAliasDao
+	AliasStatsDao  dao.AliasStatsDao
+	ApiKeyDao      dao.ApiKeyDao
+	TenantResolver *TenantResolver
+	// domains of all tenants, to be refreshed with Refresh.
+	TenantDomains    *TenantDomains
 	AliasingStrategy core.AliasingStrategy
+	ReservedAliases  *core.ReservedAliases
+	UrlNormalizer    *core.UrlNormalizer
 	CacheManager     cache.CacheManager
+	ClickRecorder    analytics.ClickRecorder
+	ClickCounter     analytics.ClickCounter
+	// decides which destination URLs aliases may point to.
+	UrlPolicy *urlpolicy.Policy
+	// limiters by the name of the limit. disabled limits have no limiter.
+	RateLimiters map[string]ratelimit.Limiter
+	// verifies bearer tokens. nil if bearer authentication isn't configured.
+	JWTVerifier auth.JWTVerifier
 }
 
-func NewAppEnv(dbManager *db.ConnectionManager, cacheManager cache.CacheManager) *AppEnv {
+func NewAppEnv(conf *config.Config, dbManager *db.ConnectionManager, cacheManager cache.CacheManager, clickRecorder analytics.ClickRecorder, clickCounter analytics.ClickCounter) *AppEnv {
 	return &AppEnv{
+		Config:           conf,
 		DBManager:        dbManager,
 		UrlAliasDao:      dao.NewUrlAliasDao(dbManager),
+		AliasStatsDao:    dao.NewAliasStatsDao(dbManager),
+		ApiKeyDao:        dao.NewApiKeyDao(dbManager),
+		TenantResolver:   NewTenantResolver(dao.NewTenantDao(dbManager), conf.TenantConfig.CacheTTL, conf.TenantConfig.CacheMaxEntries),
+		TenantDomains:    NewTenantDomains(dao.NewTenantDao(dbManager)),
 		AliasingStrategy: core.NewSimpleAliasingStrategy(),
-		CacheManager:     cacheManager,
+		ReservedAliases:  core.NewReservedAliases(conf.AliasConfig.ReservedAliases),
+		UrlNormalizer: &core.UrlNormalizer{
+			StripTrackingParams: conf.AliasConfig.StripTrackingParams,
+			TrackingParams:      conf.AliasConfig.TrackingParams,
+		},
+		CacheManager:  cacheManager,
+		ClickRecorder: clickRecorder,
+		ClickCounter:  clickCounter,
+		RateLimiters:  newRateLimiters(conf.RateLimitConfig, cacheManager),
 	}
 }
 
+// returns a copy of the AppEnv running with conf, a configuration reloaded while running.
+// urlPolicy is the policy built from conf. connections, caches and background workers are
+// shared with the copy, and the tenant cache of the shared resolver takes the TTL of conf.
+func (appEnv *AppEnv) WithConfig(conf *config.Config, urlPolicy *urlpolicy.Policy) *AppEnv {
+	next := *appEnv
+	next.Config = conf
+	next.ReservedAliases = core.NewReservedAliases(conf.AliasConfig.ReservedAliases)
+	next.UrlNormalizer = &core.UrlNormalizer{
+		StripTrackingParams: conf.AliasConfig.StripTrackingParams,
+		TrackingParams:      conf.AliasConfig.TrackingParams,
+	}
+	next.RateLimiters = newRateLimiters(conf.RateLimitConfig, appEnv.CacheManager)
+	next.UrlPolicy = urlPolicy
+
+	next.TenantResolver.SetTTL(conf.TenantConfig.CacheTTL)
+	return &next
+}
+
+// AppEnvHolder holds the AppEnv requests are served with. a configuration reload replaces
+// the AppEnv as a whole, so that a request sees the same configuration from start to end.
+type AppEnvHolder struct {
+	current atomic.Pointer[AppEnv]
+}
+
+func NewAppEnvHolder(appEnv *AppEnv) *AppEnvHolder {
+	holder := &AppEnvHolder{}
+	holder.current.Store(appEnv)
+	return holder
+}
+
+// returns the current AppEnv.
+func (h *AppEnvHolder) Load() *AppEnv {
+	return h.current.Load()
+}
+
+// replaces the AppEnv new requests are served with.
+func (h *AppEnvHolder) Store(appEnv *AppEnv) {
+	h.current.Store(appEnv)
+}
+
+func newRateLimiters(conf config.RateLimitConfig, cacheManager cache.CacheManager) map[string]ratelimit.Limiter {
+	limiters := make(map[string]ratelimit.Limiter)
+	if conf.CreateLimit > 0 {
+		limiters[RateLimitCreate] = ratelimit.NewSlidingWindowLimiter(cacheManager, RateLimitCreate, int64(conf.CreateLimit), conf.CreateWindow)
+	}
+	if conf.RedirectLimit > 0 {
+		limiters[RateLimitRedirect] = ratelimit.NewSlidingWindowLimiter(cacheManager, RateLimitRedirect, int64(conf.RedirectLimit), conf.RedirectWindow)
+	}
+	return limiters
+}
+
 // define a custom context key type for context injection
 type contextKey string
 
 // ContextAppEnvKey is the key used to store AppEnv in the context.
 const ContextAppEnvKey contextKey = "appEnv"
 
-func ContextMiddleware(appEnv *AppEnv) func(http.Handler) http.Handler {
+// ContextMiddleware stores the current AppEnv in the context of every request.
+func ContextMiddleware(appEnvs *AppEnvHolder) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.WithValue(r.Context(), ContextAppEnvKey, appEnv)
+			ctx := context.WithValue(r.Context(), ContextAppEnvKey, appEnvs.Load())
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
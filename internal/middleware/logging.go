@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/config"
 )
 
 // responseWriter is a wrapper around http.ResponseWriter to capture the status code
@@ -17,27 +19,63 @@ func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
-// LoggingMiddleware logs the incoming HTTP request and its response status.
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		// Create a responseWriter to capture the status code
-		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK} // Default to 200 OK
-
-		// Call the next handler in the chain
-		next.ServeHTTP(rw, r)
-
-		duration := time.Since(start)
-
-		// Log the request details and response status
-		log.Printf(
-			"[%s] %s %s %d %dms",
-			r.Method,
-			r.RequestURI,
-			r.Proto,
-			rw.status,
-			duration.Milliseconds(),
-		)
-	})
+// LoggingMiddleware logs the incoming HTTP request and its response status, if the status
+// is logged at the level of the current configuration: every request at info, client and
+// server errors at warn, and server errors only at error. debug logs every request together
+// with its host, client address and user agent.
+func LoggingMiddleware(appEnvs *AppEnvHolder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Create a responseWriter to capture the status code
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK} // Default to 200 OK
+
+			// Call the next handler in the chain
+			next.ServeHTTP(rw, r)
+
+			duration := time.Since(start)
+
+			level := appEnvs.Load().Config.LogConfig.Level
+			if !isStatusLogged(level, rw.status) {
+				return
+			}
+
+			if level == config.LogLevelDebug {
+				log.Printf(
+					"[%s] %s %s %d %dms host=%s remote=%s agent=%q",
+					r.Method,
+					r.RequestURI,
+					r.Proto,
+					rw.status,
+					duration.Milliseconds(),
+					r.Host,
+					r.RemoteAddr,
+					r.UserAgent(),
+				)
+				return
+			}
+
+			// Log the request details and response status
+			log.Printf(
+				"[%s] %s %s %d %dms",
+				r.Method,
+				r.RequestURI,
+				r.Proto,
+				rw.status,
+				duration.Milliseconds(),
+			)
+		})
+	}
+}
+
+func isStatusLogged(level string, status int) bool {
+	switch level {
+	case config.LogLevelWarn:
+		return status >= http.StatusBadRequest
+	case config.LogLevelError:
+		return status >= http.StatusInternalServerError
+	default:
+		return true
+	}
 }
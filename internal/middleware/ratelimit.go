@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// limits that routes can be rate limited with.
+const (
+	RateLimitCreate   = "create"
+	RateLimitRedirect = "redirect"
+)
+
+// RateLimit counts the request against the named limit and responds with
+// 429 Too Many Requests once the caller exceeds it. Authenticated callers are
+// limited by their identity, so on protected routes it runs after Authenticate
+// and before RequireScopes, which makes rejected credentials count as well.
+// Anonymous callers and invalid credentials are limited by client IP.
+//
+// Requests are let through if the limiter is unavailable, as rejecting every
+// request would be worse than not limiting them for a while.
+func RateLimit(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ChargeRateLimit(w, r, name, 1) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// ChargeRateLimit counts cost requests of the caller against the named limit, e.g. one per
+// alias of a bulk request on top of the request counted by RateLimit. it sets the rate limit
+// headers, and responds with 429 Too Many Requests and returns false once the caller exceeds
+// the limit.
+func ChargeRateLimit(w http.ResponseWriter, r *http.Request, name string, cost int64) bool {
+	appEnv, ok := r.Context().Value(ContextAppEnvKey).(*AppEnv)
+	if !ok || appEnv == nil {
+		log.Printf("RateLimit: Error accessing AppEnv.")
+		sendErrorBody(w, http.StatusInternalServerError, "Internal Server Error", "RateLimit: Error accessing AppEnv.")
+		return false
+	}
+
+	limiter := appEnv.RateLimiters[name]
+	if limiter == nil || cost <= 0 {
+		// the limit is disabled.
+		return true
+	}
+
+	key := "ip:" + appEnv.Config.ServerConfig.TrustedProxies.ClientIP(r)
+	if identity := IdentityFromContext(r.Context()); identity != nil {
+		key = "sub:" + identity.Subject
+	}
+
+	result, err := limiter.AllowN(r.Context(), key, cost, time.Now())
+	if err != nil {
+		log.Printf("Error applying the '%s' rate limit: %s", name, err.Error())
+		return true
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		sendErrorBody(w, http.StatusTooManyRequests, "Too Many Requests", "The rate limit has been exceeded, retry later.")
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
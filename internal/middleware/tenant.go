@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+// ContextTenantKey is the key used to store the *dao.Tenant of the request host in the context.
+const ContextTenantKey contextKey = "tenant"
+
+// tenant of hosts that don't belong to any tenant.
+var defaultTenant = &dao.Tenant{ID: dao.DefaultTenantID}
+
+// hosts that don't belong to a tenant are cached for at most this long, as any Host header
+// can be sent and they would otherwise crowd the tenants out of the cache.
+const unknownHostTTL = 10 * time.Second
+
+// TenantResolver maps request hosts to tenants, caching the result in memory
+// so that the tenants table isn't queried on every redirect.
+// the cache holds up to maxEntries hosts, evicting the least recently used ones.
+type TenantResolver struct {
+	tenantDao  dao.TenantDao
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// cached hosts, the most recently used first.
+	lru *list.List
+}
+
+type tenantCacheEntry struct {
+	domain    string
+	tenant    *dao.Tenant
+	expiresAt time.Time
+}
+
+func NewTenantResolver(tenantDao dao.TenantDao, ttl time.Duration, maxEntries int) *TenantResolver {
+	return &TenantResolver{
+		tenantDao:  tenantDao,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// changes how long hosts are cached. entries cached before keep their expiry.
+func (tr *TenantResolver) SetTTL(ttl time.Duration) {
+	tr.mu.Lock()
+	tr.ttl = ttl
+	tr.mu.Unlock()
+}
+
+// returns the tenant serving the host, or the default tenant if no tenant has the host as its domain.
+func (tr *TenantResolver) Resolve(ctx context.Context, host string) (*dao.Tenant, error) {
+	domain := normalizeHost(host)
+	if domain == "" {
+		return defaultTenant, nil
+	}
+
+	now := time.Now()
+	if tenant, found := tr.cached(domain, now); found {
+		return tenant, nil
+	}
+
+	tenant, err := tr.tenantDao.FindByDomain(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	ttl := tr.ttl
+	if tenant == nil {
+		// unknown hosts are cached too, as most requests come in on the default domain.
+		tenant = defaultTenant
+		ttl = min(ttl, unknownHostTTL)
+	}
+	tr.store(&tenantCacheEntry{domain: domain, tenant: tenant, expiresAt: now.Add(ttl)}, now)
+
+	return tenant, nil
+}
+
+// returns the cached tenant of the domain, removing its entry if it expired.
+func (tr *TenantResolver) cached(domain string, now time.Time) (*dao.Tenant, bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	element, found := tr.entries[domain]
+	if !found {
+		return nil, false
+	}
+	entry := element.Value.(*tenantCacheEntry)
+	if !now.Before(entry.expiresAt) {
+		tr.remove(element)
+		return nil, false
+	}
+
+	tr.lru.MoveToFront(element)
+	return entry.tenant, true
+}
+
+// caches the entry, evicting the least recently used entries beyond maxEntries, and the
+// expired ones among the least recently used. must be called holding mu.
+func (tr *TenantResolver) store(entry *tenantCacheEntry, now time.Time) {
+	if element, found := tr.entries[entry.domain]; found {
+		tr.remove(element)
+	}
+	tr.entries[entry.domain] = tr.lru.PushFront(entry)
+
+	for element := tr.lru.Back(); element != nil; element = tr.lru.Back() {
+		if tr.lru.Len() <= tr.maxEntries && now.Before(element.Value.(*tenantCacheEntry).expiresAt) {
+			break
+		}
+		tr.remove(element)
+	}
+}
+
+func (tr *TenantResolver) remove(element *list.Element) {
+	tr.lru.Remove(element)
+	delete(tr.entries, element.Value.(*tenantCacheEntry).domain)
+}
+
+// strips the port and lowercases the host.
+func normalizeHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// returns the tenant of the request host, or the default tenant if none was resolved.
+func TenantFromContext(ctx context.Context) *dao.Tenant {
+	if tenant, ok := ctx.Value(ContextTenantKey).(*dao.Tenant); ok {
+		return tenant
+	}
+	return defaultTenant
+}
+
+// TenantMiddleware resolves the tenant of the request's Host header and stores it in the context.
+// It must run after ContextMiddleware.
+func TenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appEnv, ok := r.Context().Value(ContextAppEnvKey).(*AppEnv)
+		if !ok || appEnv == nil {
+			log.Printf("TenantMiddleware: Error accessing AppEnv.")
+			sendErrorBody(w, http.StatusInternalServerError, "Internal Server Error", "TenantMiddleware: Error accessing AppEnv.")
+			return
+		}
+
+		tenant, err := appEnv.TenantResolver.Resolve(r.Context(), r.Host)
+		if err != nil {
+			log.Printf("Error resolving tenant of host '%s': %s", r.Host, err.Error())
+			sendErrorBody(w, http.StatusInternalServerError, "Internal Server Error", "Unexpected error while resolving the tenant of the request.")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ContextTenantKey, tenant)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
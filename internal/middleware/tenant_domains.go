@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+// TenantDomains holds the domains of all tenants, loaded as a whole with Refresh. unlike the
+// TenantResolver, looking a host up never queries the database, so it can be used for hosts
+// chosen by users, e.g. the destinations of new aliases.
+type TenantDomains struct {
+	tenantDao dao.TenantDao
+	domains   atomic.Pointer[map[string]bool]
+}
+
+func NewTenantDomains(tenantDao dao.TenantDao) *TenantDomains {
+	td := &TenantDomains{tenantDao: tenantDao}
+	td.domains.Store(&map[string]bool{})
+	return td
+}
+
+// reloads the domains of the tenants. the previous domains are kept if they can't be loaded.
+func (td *TenantDomains) Refresh(ctx context.Context) error {
+	domains, err := td.tenantDao.ListDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		loaded[normalizeHost(domain)] = true
+	}
+	td.domains.Store(&loaded)
+	return nil
+}
+
+// returns whether the host is the domain of a tenant, as of the last refresh.
+func (td *TenantDomains) Contains(host string) bool {
+	return (*td.domains.Load())[normalizeHost(host)]
+}
@@ -43,12 +43,7 @@ func Validate[T any](next func(http.ResponseWriter, *http.Request, T)) http.Hand
 			return
 		}
 
-		if err := validate.Struct(payload); err != nil {
-			var msg []string
-			for _, valErr := range err.(validator.ValidationErrors) {
-				fieldName := valErr.Field()
-				msg = append(msg, fmt.Sprintf("%s: %s", fieldName, valErr.Tag())) // Simplified message
-			}
+		if msg := ValidateStruct(payload); msg != nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(&ValidationError{
@@ -61,3 +56,24 @@ func Validate[T any](next func(http.ResponseWriter, *http.Request, T)) http.Hand
 		next(w, r, payload)
 	}
 }
+
+// ValidateStruct checks the struct against its validate tags, and returns one message
+// per failed field, or nil if it is valid.
+func ValidateStruct(payload any) []string {
+	err := validate.Struct(payload)
+	if err == nil {
+		return nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	var msg []string
+	for _, valErr := range validationErrors {
+		fieldName := valErr.Field()
+		msg = append(msg, fmt.Sprintf("%s: %s", fieldName, valErr.Tag())) // Simplified message
+	}
+	return msg
+}
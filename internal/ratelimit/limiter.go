@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/cache"
+)
+
+// cache store holding the per-window request counters.
+const RATE_LIMIT_CACHE_STORE = "rate_limits"
+
+// Result describes the state of a caller's limit after a request.
+type Result struct {
+	Allowed bool
+	// requests allowed per window.
+	Limit int64
+	// requests left in the current window.
+	Remaining int64
+	// time until the current window ends.
+	Reset time.Duration
+	// time after which a rejected request may be retried. zero if the request was allowed.
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// counts a request of the caller identified by key and reports whether it is within the limit.
+	Allow(ctx context.Context, key string, now time.Time) (*Result, error)
+
+	// like Allow, but counts cost requests at once, e.g. for a request creating several aliases.
+	AllowN(ctx context.Context, key string, cost int64, now time.Time) (*Result, error)
+}
+
+// slidingWindowLimiter approximates a sliding window by weighting the count of the
+// previous fixed window by how much of it still overlaps the sliding window.
+// counters live in the cache, so the limit is shared by every instance of the service.
+type slidingWindowLimiter struct {
+	cacheManager cache.CacheManager
+	name         string
+	limit        int64
+	window       time.Duration
+}
+
+// creates a Limiter allowing limit requests per window for every key.
+// name separates the counters of limiters sharing the cache.
+func NewSlidingWindowLimiter(cacheManager cache.CacheManager, name string, limit int64, window time.Duration) Limiter {
+	return &slidingWindowLimiter{
+		cacheManager: cacheManager,
+		name:         name,
+		limit:        limit,
+		window:       window,
+	}
+}
+
+func (l *slidingWindowLimiter) counterKey(key string, windowStart time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", l.name, key, windowStart.Unix())
+}
+
+func (l *slidingWindowLimiter) Allow(ctx context.Context, key string, now time.Time) (*Result, error) {
+	return l.AllowN(ctx, key, 1, now)
+}
+
+// rejected requests are counted as well, so that callers who keep retrying stay limited.
+func (l *slidingWindowLimiter) AllowN(ctx context.Context, key string, cost int64, now time.Time) (*Result, error) {
+	windowStart := now.Truncate(l.window)
+	elapsed := float64(now.Sub(windowStart)) / float64(l.window)
+
+	// counters are kept for two windows, as they are still read as the previous window.
+	current, err := l.cacheManager.IncrementBy(ctx, RATE_LIMIT_CACHE_STORE, l.counterKey(key, windowStart), cost, 2*l.window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment rate limit counter: %w", err)
+	}
+
+	previous, err := l.previousCount(ctx, key, windowStart.Add(-l.window))
+	if err != nil {
+		return nil, err
+	}
+
+	estimated := float64(previous)*(1-elapsed) + float64(current)
+
+	result := &Result{
+		Allowed:   estimated <= float64(l.limit),
+		Limit:     l.limit,
+		Remaining: max(0, l.limit-int64(math.Ceil(estimated))),
+		Reset:     windowStart.Add(l.window).Sub(now),
+	}
+
+	if !result.Allowed {
+		result.RetryAfter = l.retryAfter(previous, current, cost, elapsed)
+	}
+	return result, nil
+}
+
+func (l *slidingWindowLimiter) previousCount(ctx context.Context, key string, windowStart time.Time) (int64, error) {
+	value, err := l.cacheManager.Get(ctx, RATE_LIMIT_CACHE_STORE, l.counterKey(key, windowStart))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rate limit counter: %w", err)
+	}
+	if value == nil {
+		return 0, nil
+	}
+
+	count, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate limit counter '%v': %w", value, err)
+	}
+	return count, nil
+}
+
+// returns how long it takes, without further requests, until a retry of the same
+// cost falls within the limit.
+func (l *slidingWindowLimiter) retryAfter(previous int64, current int64, cost int64, elapsed float64) time.Duration {
+	var windows float64
+	if current+cost <= l.limit && previous > 0 {
+		// the weight of the previous window still has to decay.
+		windows = 1 - float64(l.limit-current-cost)/float64(previous) - elapsed
+	} else {
+		// the current window becomes the previous one, and has to decay in turn.
+		windows = (1 - elapsed) + (1 - float64(max(0, l.limit-cost))/float64(current))
+	}
+
+	retryAfter := time.Duration(windows * float64(l.window))
+	if retryAfter < time.Second {
+		return time.Second
+	}
+	return retryAfter
+}
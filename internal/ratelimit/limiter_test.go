@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/cache"
+)
+
+// memoryCache keeps the counters of the limiter in memory. only the methods used
+// by the limiter are implemented, the others panic through the nil interface.
+type memoryCache struct {
+	cache.CacheManager
+	values map[string]int64
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]int64)}
+}
+
+func (m *memoryCache) Get(ctx context.Context, keyStore string, key string) (interface{}, error) {
+	value, ok := m.values[keyStore+":"+key]
+	if !ok {
+		return nil, nil
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+func (m *memoryCache) IncrementBy(ctx context.Context, keyStore string, key string, delta int64, ttl time.Duration) (int64, error) {
+	m.values[keyStore+":"+key] += delta
+	return m.values[keyStore+":"+key], nil
+}
+
+var windowStart = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSlidingWindowLimiterWithinWindow(t *testing.T) {
+	limiter := NewSlidingWindowLimiter(newMemoryCache(), "create", 10, time.Minute)
+	ctx := context.Background()
+	now := windowStart.Add(15 * time.Second)
+
+	for i := 1; i <= 10; i++ {
+		result, err := limiter.Allow(ctx, "sub:a", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != int64(10-i) {
+			t.Fatalf("request %d: allowed %t with %d remaining, want allowed with %d", i, result.Allowed, result.Remaining, 10-i)
+		}
+		if result.Limit != 10 || result.Reset != 45*time.Second {
+			t.Errorf("request %d: limit %d and reset %s, want 10 and 45s", i, result.Limit, result.Reset)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "sub:a", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("request 11: allowed %t with %d remaining, want rejected with 0", result.Allowed, result.Remaining)
+	}
+	// a retry only fits once the 11 requests of this window have become the previous
+	// window and decayed to 9: 45s + 60s * 2/11.
+	if want := 45*time.Second + 2*time.Minute/11; absDuration(result.RetryAfter-want) > time.Millisecond {
+		t.Errorf("request 11: retry after %s, want %s", result.RetryAfter, want)
+	}
+
+	// other callers have limits of their own.
+	if result, err := limiter.Allow(ctx, "sub:b", now); err != nil || !result.Allowed {
+		t.Errorf("the request of another caller was rejected: %+v, %v", result, err)
+	}
+}
+
+func TestSlidingWindowLimiterWeighsPreviousWindow(t *testing.T) {
+	limiter := NewSlidingWindowLimiter(newMemoryCache(), "create", 10, time.Minute)
+	ctx := context.Background()
+
+	// 10 requests at the end of the previous window.
+	if _, err := limiter.AllowN(ctx, "ip:1.2.3.4", 10, windowStart.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// halfway through the window, the previous one still counts for 5 requests.
+	now := windowStart.Add(30 * time.Second)
+	for i := 1; i <= 5; i++ {
+		result, err := limiter.Allow(ctx, "ip:1.2.3.4", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != int64(5-i) {
+			t.Fatalf("request %d: allowed %t with %d remaining, want allowed with %d", i, result.Allowed, result.Remaining, 5-i)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "ip:1.2.3.4", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Fatalf("request 6 was allowed, want it rejected")
+	}
+	// a retry fits once 7 + 10 * (1 - elapsed) <= 10, at 0.7 of the window, 12s later.
+	if want := 12 * time.Second; absDuration(result.RetryAfter-want) > time.Millisecond {
+		t.Errorf("request 6: retry after %s, want %s", result.RetryAfter, want)
+	}
+
+	// the retry is allowed once Retry-After has passed, as rounded up in the header.
+	if result, err := limiter.Allow(ctx, "ip:1.2.3.4", now.Add(result.RetryAfter.Round(time.Second))); err != nil || !result.Allowed {
+		t.Errorf("the request after Retry-After was rejected: %+v, %v", result, err)
+	}
+}
+
+func TestSlidingWindowLimiterAllowN(t *testing.T) {
+	limiter := NewSlidingWindowLimiter(newMemoryCache(), "create", 10, time.Minute)
+	ctx := context.Background()
+
+	result, err := limiter.AllowN(ctx, "sub:a", 4, windowStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed || result.Remaining != 6 {
+		t.Errorf("a cost of 4: allowed %t with %d remaining, want allowed with 6", result.Allowed, result.Remaining)
+	}
+
+	result, err = limiter.AllowN(ctx, "sub:a", 7, windowStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Errorf("a cost of 7 with 6 remaining was allowed, want it rejected")
+	}
+	// 11 of 10 requests in this window, a retry of 7 fits once they decayed to 3: 60s + 60s * 8/11.
+	if want := time.Minute + 8*time.Minute/11; absDuration(result.RetryAfter-want) > time.Millisecond {
+		t.Errorf("retry after %s, want %s", result.RetryAfter, want)
+	}
+}
+
+func TestSlidingWindowLimiterRetryAfterAtLeastOneSecond(t *testing.T) {
+	limiter := NewSlidingWindowLimiter(newMemoryCache(), "create", 10, time.Minute)
+	ctx := context.Background()
+
+	// 240 requests of the previous window still count for 2 half a second before the end of this one.
+	if _, err := limiter.AllowN(ctx, "sub:a", 240, windowStart.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.AllowN(ctx, "sub:a", 8, windowStart.Add(58*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := limiter.Allow(ctx, "sub:a", windowStart.Add(59500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.RetryAfter != time.Second {
+		t.Errorf("allowed %t and retry after %s, want rejected and 1s", result.Allowed, result.RetryAfter)
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
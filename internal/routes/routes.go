@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
+	"github.com/shashwatrathod/url-shortner/internal/auth"
 	"github.com/shashwatrathod/url-shortner/internal/handlers"
 	"github.com/shashwatrathod/url-shortner/internal/middleware"
 )
@@ -9,6 +12,22 @@ import (
 func RegisterRoutes(router *mux.Router) {
 	r := router.PathPrefix("/api").Subrouter()
 	r.HandleFunc("/health", handlers.HealthHandler).Methods("GET")
-	r.HandleFunc("/create", middleware.Validate(handlers.CreateUrlAliasHandler)).Methods("POST")
-	r.HandleFunc("/{alias}", handlers.GetUrlAliasHandler).Methods("GET")
+
+	// management routes require an API key with the listed scopes. create requests are limited
+	// before the scopes are checked, so that requests with invalid credentials are limited too.
+	r.Handle("/create", middleware.Authenticate(middleware.RateLimit(middleware.RateLimitCreate)(middleware.RequireScopes(auth.ScopeCreate)(middleware.Validate(handlers.CreateUrlAliasHandler))))).Methods("POST")
+	r.Handle("/create/bulk", middleware.Authenticate(middleware.RateLimit(middleware.RateLimitCreate)(middleware.RequireScopes(auth.ScopeCreate)(http.HandlerFunc(handlers.CreateUrlAliasesBulkHandler))))).Methods("POST")
+	r.Handle("/aliases", middleware.RequireScopes()(http.HandlerFunc(handlers.ListAliasesHandler))).Methods("GET")
+	r.Handle("/aliases/export", middleware.RequireScopes(auth.ScopeAdmin)(http.HandlerFunc(handlers.ExportAliasesHandler))).Methods("GET")
+	r.Handle("/aliases/import", middleware.RequireScopes(auth.ScopeAdmin)(http.HandlerFunc(handlers.ImportAliasesHandler))).Methods("POST")
+	r.Handle("/aliases/{alias}/stats", middleware.RequireScopes(auth.ScopeReadStats)(http.HandlerFunc(handlers.GetAliasStatsHandler))).Methods("GET")
+	r.Handle("/keys", middleware.RequireScopes(auth.ScopeAdmin)(middleware.Validate(handlers.CreateApiKeyHandler))).Methods("POST")
+	r.Handle("/keys/{id}", middleware.RequireScopes(auth.ScopeAdmin)(http.HandlerFunc(handlers.RevokeApiKeyHandler))).Methods("DELETE")
+
+	// kept for links shared before redirects moved to the root path.
+	r.Handle("/{alias}", middleware.RateLimit(middleware.RateLimitRedirect)(http.HandlerFunc(handlers.DeprecatedGetUrlAliasHandler))).Methods("GET")
+
+	// the redirect route is public, and is registered after the API routes so that it
+	// never shadows them. aliases are kept off their paths by core.ReservedAliases.
+	router.Handle("/{alias}", middleware.RateLimit(middleware.RateLimitRedirect)(http.HandlerFunc(handlers.GetUrlAliasHandler))).Methods("GET")
 }
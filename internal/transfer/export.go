@@ -0,0 +1,36 @@
+package transfer
+
+import (
+	"context"
+
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+// writes the aliases matching the filter across all shards to w, and returns how many were written.
+// records are streamed as they are read, so exports don't have to fit in memory.
+func Export(ctx context.Context, urlAliasDao dao.UrlAliasDao, w RecordWriter, filter dao.UrlAliasExportFilter) (int, error) {
+	count := 0
+
+	err := urlAliasDao.ExportUrlAliases(ctx, filter, func(urlAlias *dao.UrlAlias) error {
+		createdAt := urlAlias.CreatedAt
+		if err := w.Write(&Record{
+			TenantID:     urlAlias.TenantID,
+			Alias:        urlAlias.Alias,
+			OriginalUrl:  urlAlias.OriginalURL,
+			RedirectCode: urlAlias.RedirectCode,
+			OwnerID:      urlAlias.OwnerID,
+			ExpiresAt:    urlAlias.ExpiresAt,
+			CreatedAt:    &createdAt,
+		}); err != nil {
+			return err
+		}
+
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, w.Flush()
+}
@@ -0,0 +1,235 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/urlpolicy"
+)
+
+const (
+	// number of records written per call to the DAO.
+	importBatchSize = 1000
+	// number of record errors kept in an ImportResult.
+	maxImportErrors = 100
+	// maximum length of an alias, as stored by the database.
+	maxAliasLength = 8
+	// maximum length of a tenant identifier.
+	maxTenantIDLength = 32
+)
+
+// ImportOptions configures an import.
+type ImportOptions struct {
+	Policy dao.ImportConflictPolicy
+
+	// if set, records without a tenant are imported into TenantID, and records of other
+	// tenants are rejected. otherwise records without a tenant belong to the default tenant.
+	TenantID string
+
+	// normalizes the original URLs, as the exporting service may have normalized them differently.
+	Normalizer *core.UrlNormalizer
+
+	// rejects records whose alias would shadow a route of the service.
+	ReservedAliases *core.ReservedAliases
+
+	// rejects records whose original URL new aliases couldn't point to.
+	UrlPolicy *urlpolicy.Policy
+	// host the import was sent to, as links to it are rejected by the URL policy.
+	RequestHost string
+
+	// redirect code of records without one.
+	DefaultRedirectCode int
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+	// errors of the first failed records.
+	Errors []RecordError `json:"errors,omitempty"`
+
+	// tenant qualified keys of the updated aliases, whose cached records are stale.
+	UpdatedKeys []string `json:"-"`
+}
+
+// InputError reports an input that can't be read any further, e.g. a CSV without a header.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+func (r *ImportResult) addError(err *RecordError) {
+	r.Failed++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, *err)
+	}
+}
+
+// writes the records read from reader in batches, resolving existing aliases with the conflict policy.
+// invalid records are counted and reported in the result instead of failing the import.
+// the result reflects the records written before an error is returned; as imports are idempotent,
+// a failed import can be run again. an *InputError is returned if the input can't be read.
+func Import(ctx context.Context, urlAliasDao dao.UrlAliasDao, reader RecordReader, opts ImportOptions) (*ImportResult, error) {
+	if !IsKnownPolicy(opts.Policy) {
+		return nil, fmt.Errorf("unsupported conflict policy '%s'", opts.Policy)
+	}
+
+	result := &ImportResult{}
+	batch := make([]dao.UrlAlias, 0, importBatchSize)
+	// position of every alias of the batch, as the DAO expects each alias at most once.
+	positions := make(map[string]int)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		batchResult, err := urlAliasDao.ImportUrlAliases(ctx, batch, opts.Policy)
+		if batchResult != nil {
+			result.Inserted += batchResult.Inserted
+			result.Updated += batchResult.Updated
+			result.Skipped += batchResult.Skipped
+			result.UpdatedKeys = append(result.UpdatedKeys, batchResult.UpdatedKeys...)
+		}
+
+		batch = batch[:0]
+		clear(positions)
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			result.addError(recordErr)
+			continue
+		}
+		if err != nil {
+			return result, &InputError{Err: err}
+		}
+
+		urlAlias, message := opts.toUrlAlias(ctx, record)
+		if message != "" {
+			result.addError(&RecordError{Line: reader.Line(), Message: message})
+			continue
+		}
+
+		// a later record of the same alias replaces the earlier one.
+		key := dao.AliasKey(urlAlias.TenantID, urlAlias.Alias)
+		if position, found := positions[key]; found {
+			batch[position] = *urlAlias
+			result.Skipped++
+			continue
+		}
+		positions[key] = len(batch)
+		batch = append(batch, *urlAlias)
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// converts the record into the alias to write, or returns why it can't be imported.
+func (opts *ImportOptions) toUrlAlias(ctx context.Context, record *Record) (*dao.UrlAlias, string) {
+	tenantID := record.TenantID
+	if tenantID == "" {
+		tenantID = opts.TenantID
+	}
+	if tenantID == "" {
+		tenantID = dao.DefaultTenantID
+	}
+	if opts.TenantID != "" && tenantID != opts.TenantID {
+		return nil, fmt.Sprintf("the record belongs to tenant '%s', not '%s'", tenantID, opts.TenantID)
+	}
+	if len(tenantID) > maxTenantIDLength || strings.ContainsAny(tenantID, "/|:") {
+		return nil, fmt.Sprintf("invalid tenant '%s'", tenantID)
+	}
+
+	if record.Alias == "" || len(record.Alias) > maxAliasLength || strings.Contains(record.Alias, "/") {
+		return nil, fmt.Sprintf("the alias must be 1 to %d characters without '/'", maxAliasLength)
+	}
+	if opts.ReservedAliases != nil && opts.ReservedAliases.IsReserved(record.Alias) {
+		return nil, fmt.Sprintf("the alias '%s' is reserved", record.Alias)
+	}
+
+	if parsed, err := url.Parse(record.OriginalUrl); err != nil || parsed.Scheme == "" {
+		return nil, "'originalUrl' must be an absolute URL"
+	}
+	if opts.UrlPolicy != nil {
+		violation, err := opts.UrlPolicy.Check(ctx, record.OriginalUrl, opts.RequestHost)
+		if err != nil {
+			return nil, fmt.Sprintf("'originalUrl' couldn't be checked: %s", err)
+		}
+		if violation != nil {
+			return nil, fmt.Sprintf("'originalUrl' isn't allowed (%s): %s", violation.Rule, violation.Message)
+		}
+	}
+	normalizedUrl := record.OriginalUrl
+	if opts.Normalizer != nil {
+		normalized, err := opts.Normalizer.Normalize(record.OriginalUrl)
+		if err != nil {
+			return nil, "'originalUrl' can't be parsed"
+		}
+		normalizedUrl = normalized
+	}
+
+	redirectCode := record.RedirectCode
+	if redirectCode == 0 {
+		redirectCode = opts.DefaultRedirectCode
+	}
+	if !config.IsSupportedRedirectCode(redirectCode) {
+		return nil, fmt.Sprintf("unsupported redirect code %d", redirectCode)
+	}
+
+	urlAlias := &dao.UrlAlias{
+		TenantID:      tenantID,
+		Alias:         record.Alias,
+		OriginalURL:   record.OriginalUrl,
+		NormalizedURL: normalizedUrl,
+		RedirectCode:  redirectCode,
+		OwnerID:       record.OwnerID,
+		ExpiresAt:     record.ExpiresAt,
+	}
+	if record.CreatedAt != nil {
+		urlAlias.CreatedAt = *record.CreatedAt
+	}
+	return urlAlias, ""
+}
+
+// returns true if the policy is one of dao.ImportConflictPolicies.
+func IsKnownPolicy(policy dao.ImportConflictPolicy) bool {
+	for _, known := range dao.ImportConflictPolicies {
+		if policy == known {
+			return true
+		}
+	}
+	return false
+}
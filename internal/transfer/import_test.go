@@ -0,0 +1,126 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shashwatrathod/url-shortner/internal/core"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+)
+
+// fakeUrlAliasDao keeps imported aliases in memory. only ImportUrlAliases is
+// implemented, the other methods panic through the nil interface.
+type fakeUrlAliasDao struct {
+	dao.UrlAliasDao
+	aliases map[string]dao.UrlAlias
+	batches [][]dao.UrlAlias
+}
+
+// imports like ImportConflictSkip, keeping existing aliases.
+func (d *fakeUrlAliasDao) ImportUrlAliases(ctx context.Context, urlAliases []dao.UrlAlias, policy dao.ImportConflictPolicy) (*dao.UrlAliasImportResult, error) {
+	d.batches = append(d.batches, append([]dao.UrlAlias(nil), urlAliases...))
+
+	result := &dao.UrlAliasImportResult{}
+	for _, urlAlias := range urlAliases {
+		key := dao.AliasKey(urlAlias.TenantID, urlAlias.Alias)
+		if _, found := d.aliases[key]; found {
+			result.Skipped++
+			continue
+		}
+		d.aliases[key] = urlAlias
+		result.Inserted++
+	}
+	return result, nil
+}
+
+func TestImport(t *testing.T) {
+	urlAliasDao := &fakeUrlAliasDao{aliases: map[string]dao.UrlAlias{
+		dao.AliasKey("acme", "exists"): {TenantID: "acme", Alias: "exists", OriginalURL: "https://example.com/old"},
+	}}
+	input := strings.Join([]string{
+		`{"alias":"a1","originalUrl":"https://example.com/1"}`,
+		`{"alias":"a1","originalUrl":"HTTPS://Example.com/2","redirectCode":301}`,
+		`{"tenantId":"other","alias":"b","originalUrl":"https://example.com/b"}`,
+		`{"alias":"api","originalUrl":"https://example.com/api"}`,
+		`{"alias":"toolong12","originalUrl":"https://example.com/long"}`,
+		`{"alias":`,
+		`{"tenantId":"acme","alias":"exists","originalUrl":"https://example.com/new"}`,
+		`{"alias":"c","originalUrl":"example.com/c"}`,
+		`{"alias":"d","originalUrl":"https://example.com/d","redirectCode":200}`,
+		`{"alias":"e","originalUrl":"https://example.com/e","ownerId":"apikey:1"}`,
+	}, "\n")
+
+	reader, err := NewRecordReader(strings.NewReader(input), FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Import(context.Background(), urlAliasDao, reader, ImportOptions{
+		Policy:              dao.ImportConflictSkip,
+		TenantID:            "acme",
+		Normalizer:          &core.UrlNormalizer{},
+		ReservedAliases:     core.NewReservedAliases(nil),
+		DefaultRedirectCode: 302,
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if result.Inserted != 2 || result.Updated != 0 || result.Skipped != 2 || result.Failed != 6 {
+		t.Errorf("Import inserted %d, updated %d, skipped %d and failed %d, want 2, 0, 2 and 6",
+			result.Inserted, result.Updated, result.Skipped, result.Failed)
+	}
+
+	var errorLines []int
+	for _, recordErr := range result.Errors {
+		errorLines = append(errorLines, recordErr.Line)
+	}
+	if want := []int{3, 4, 5, 6, 8, 9}; !reflect.DeepEqual(errorLines, want) {
+		t.Errorf("Import reported errors on lines %v, want %v: %+v", errorLines, want, result.Errors)
+	}
+	for i, want := range []string{"tenant 'other', not 'acme'", "reserved", "1 to 8 characters", "malformed", "absolute URL", "redirect code 200"} {
+		if i < len(result.Errors) && !strings.Contains(result.Errors[i].Message, want) {
+			t.Errorf("error %d is %q, want it to mention %q", i, result.Errors[i].Message, want)
+		}
+	}
+
+	// the later record of a repeated alias replaces the earlier one within the batch.
+	if len(urlAliasDao.batches) != 1 || len(urlAliasDao.batches[0]) != 3 {
+		t.Fatalf("Import wrote the batches %+v, want a single batch of 3 aliases", urlAliasDao.batches)
+	}
+	want := dao.UrlAlias{TenantID: "acme", Alias: "a1", OriginalURL: "HTTPS://Example.com/2", NormalizedURL: "https://example.com/2", RedirectCode: 301}
+	if got := urlAliasDao.aliases[dao.AliasKey("acme", "a1")]; !reflect.DeepEqual(got, want) {
+		t.Errorf("imported %+v, want %+v", got, want)
+	}
+	if got := urlAliasDao.aliases[dao.AliasKey("acme", "exists")]; got.OriginalURL != "https://example.com/old" {
+		t.Errorf("the existing alias was replaced by %+v", got)
+	}
+	if got := urlAliasDao.aliases[dao.AliasKey("acme", "e")]; got.RedirectCode != 302 || got.OwnerID != "apikey:1" {
+		t.Errorf("imported %+v, want the default redirect code and the owner", got)
+	}
+}
+
+func TestImportRejectsUnknownPolicy(t *testing.T) {
+	reader, err := NewRecordReader(strings.NewReader(""), FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(context.Background(), &fakeUrlAliasDao{}, reader, ImportOptions{Policy: "merge"}); err == nil {
+		t.Errorf("Import with an unknown policy succeeded, want an error")
+	}
+}
+
+func TestImportReportsUnreadableInput(t *testing.T) {
+	reader, err := NewRecordReader(strings.NewReader("alias,url\n"), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Import(context.Background(), &fakeUrlAliasDao{aliases: map[string]dao.UrlAlias{}}, reader, ImportOptions{Policy: dao.ImportConflictSkip})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("Import returned %v, want an *InputError", err)
+	}
+}
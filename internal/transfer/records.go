@@ -0,0 +1,286 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the serialization of exported aliases.
+type Format string
+
+const (
+	// one JSON record per line.
+	FormatNDJSON Format = "ndjson"
+	// a header row followed by one record per row, see csvColumns.
+	FormatCSV Format = "csv"
+)
+
+// returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case FormatNDJSON, "jsonl":
+		return FormatNDJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s', use ndjson or csv", name)
+	}
+}
+
+// returns the media type of files in the format.
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Record is an exported alias.
+type Record struct {
+	TenantID     string     `json:"tenantId"`
+	Alias        string     `json:"alias"`
+	OriginalUrl  string     `json:"originalUrl"`
+	RedirectCode int        `json:"redirectCode"`
+	OwnerID      string     `json:"ownerId,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+}
+
+// columns of CSV exports, named like the JSON fields of Record.
+var csvColumns = []string{"tenantId", "alias", "originalUrl", "redirectCode", "ownerId", "expiresAt", "createdAt"}
+
+// RecordError reports a record that couldn't be read or imported. the rest of the input is still usable.
+type RecordError struct {
+	// line of the record in the input, starting at 1.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// RecordWriter serializes records in a Format.
+type RecordWriter interface {
+	Write(record *Record) error
+
+	// writes buffered records to the underlying writer.
+	Flush() error
+}
+
+// RecordReader reads records in a Format.
+type RecordReader interface {
+	// returns the next record, or io.EOF after the last one.
+	// a *RecordError is returned for malformed records, which can be skipped by reading on.
+	Read() (*Record, error)
+
+	// returns the line of the record last read, starting at 1.
+	Line() int
+}
+
+// returns a RecordWriter writing records in the format to w.
+func NewRecordWriter(w io.Writer, format Format) (RecordWriter, error) {
+	switch format {
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonRecordWriter{writer: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case FormatCSV:
+		return &csvRecordWriter{writer: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// returns a RecordReader reading records in the format from r.
+func NewRecordReader(r io.Reader, format Format) (RecordReader, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonRecordReader{reader: bufio.NewReader(r)}, nil
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		return &csvRecordReader{reader: reader}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+type ndjsonRecordWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *ndjsonRecordWriter) Write(record *Record) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *ndjsonRecordWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+type ndjsonRecordReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (r *ndjsonRecordReader) Read() (*Record, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			return nil, err
+		}
+		r.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, &RecordError{Line: r.line, Message: "malformed JSON record"}
+		}
+		return &record, nil
+	}
+}
+
+func (r *ndjsonRecordReader) Line() int {
+	return r.line
+}
+
+type csvRecordWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvRecordWriter) Write(record *Record) error {
+	if !w.headerWritten {
+		if err := w.writer.Write(csvColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	return w.writer.Write([]string{
+		record.TenantID,
+		record.Alias,
+		record.OriginalUrl,
+		strconv.Itoa(record.RedirectCode),
+		record.OwnerID,
+		formatTime(record.ExpiresAt),
+		formatTime(record.CreatedAt),
+	})
+}
+
+func (w *csvRecordWriter) Flush() error {
+	if !w.headerWritten {
+		// an empty export still names its columns.
+		if err := w.writer.Write(csvColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type csvRecordReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func (r *csvRecordReader) Line() int {
+	return r.line
+}
+
+func (r *csvRecordReader) Read() (*Record, error) {
+	if r.columns == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("the CSV must start with a header row")
+			}
+			return nil, err
+		}
+
+		r.columns = make(map[string]int)
+		for i, name := range header {
+			r.columns[strings.TrimSpace(name)] = i
+		}
+		for _, required := range []string{"alias", "originalUrl"} {
+			if _, found := r.columns[required]; !found {
+				return nil, fmt.Errorf("the CSV header must contain an '%s' column", required)
+			}
+		}
+	}
+
+	row, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && !errors.Is(parseErr.Err, csv.ErrQuote) {
+			r.line = parseErr.StartLine
+			return nil, &RecordError{Line: parseErr.StartLine, Message: parseErr.Err.Error()}
+		}
+		return nil, err
+	}
+
+	r.line, _ = r.reader.FieldPos(0)
+	line := r.line
+	column := func(name string) string {
+		if i, found := r.columns[name]; found && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	record := &Record{
+		TenantID:    column("tenantId"),
+		Alias:       column("alias"),
+		OriginalUrl: column("originalUrl"),
+		OwnerID:     column("ownerId"),
+	}
+
+	if redirectCode := column("redirectCode"); redirectCode != "" {
+		if record.RedirectCode, err = strconv.Atoi(redirectCode); err != nil {
+			return nil, &RecordError{Line: line, Message: "'redirectCode' must be a number"}
+		}
+	}
+	if record.ExpiresAt, err = parseTime(column("expiresAt")); err != nil {
+		return nil, &RecordError{Line: line, Message: "'expiresAt' must be an RFC3339 timestamp"}
+	}
+	if record.CreatedAt, err = parseTime(column("createdAt")); err != nil {
+		return nil, &RecordError{Line: line, Message: "'createdAt' must be an RFC3339 timestamp"}
+	}
+	return record, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// returns nil for an empty value.
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
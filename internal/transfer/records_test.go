@@ -0,0 +1,162 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reads every record, collecting the lines of malformed ones.
+func readAll(t *testing.T, reader RecordReader) ([]Record, []int) {
+	t.Helper()
+
+	var records []Record
+	var errorLines []int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, errorLines
+		}
+
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			errorLines = append(errorLines, recordErr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		records = append(records, *record)
+	}
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	expiresAt := time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)
+	createdAt := time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC)
+	records := []Record{
+		{TenantID: "acme", Alias: "aBcDeFg1", OriginalUrl: "https://example.com/a?b=1,2", RedirectCode: 301, OwnerID: "apikey:1", ExpiresAt: &expiresAt, CreatedAt: &createdAt},
+		{TenantID: "default", Alias: "x", OriginalUrl: "https://example.com/\"quoted\"", RedirectCode: 302},
+	}
+
+	for _, format := range []Format{FormatNDJSON, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buffer bytes.Buffer
+			writer, err := NewRecordWriter(&buffer, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range records {
+				if err := writer.Write(&records[i]); err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+
+			reader, err := NewRecordReader(&buffer, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, errorLines := readAll(t, reader)
+			if len(errorLines) != 0 {
+				t.Errorf("malformed records on lines %v", errorLines)
+			}
+			if !reflect.DeepEqual(got, records) {
+				t.Errorf("read %+v, want %+v", got, records)
+			}
+		})
+	}
+}
+
+func TestEmptyCSVExportHasHeader(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := NewRecordWriter(&buffer, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := strings.Join(csvColumns, ",") + "\n"; buffer.String() != want {
+		t.Errorf("an empty export is %q, want %q", buffer.String(), want)
+	}
+}
+
+func TestRecordReaderReportsMalformedRecords(t *testing.T) {
+	tests := []struct {
+		name           string
+		format         Format
+		input          string
+		wantAliases    []string
+		wantErrorLines []int
+	}{
+		{
+			name:   "NDJSON",
+			format: FormatNDJSON,
+			input: `{"alias":"a","originalUrl":"https://example.com/a"}
+{"alias":
+` + "\n" + `{"alias":"b","originalUrl":"https://example.com/b","redirectCode":"301"}
+{"alias":"c","originalUrl":"https://example.com/c"}`,
+			wantAliases:    []string{"a", "c"},
+			wantErrorLines: []int{2, 4},
+		},
+		{
+			name:   "CSV",
+			format: FormatCSV,
+			input: `alias, originalUrl, redirectCode, expiresAt
+a,https://example.com/a,301,
+b,https://example.com/b,abc,
+c,https://example.com/c,,tomorrow
+d,https://example.com/d"x,,
+e,https://example.com/e
+`,
+			wantAliases:    []string{"a", "e"},
+			wantErrorLines: []int{3, 4, 5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader, err := NewRecordReader(strings.NewReader(test.input), test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			records, errorLines := readAll(t, reader)
+			var aliases []string
+			for _, record := range records {
+				aliases = append(aliases, record.Alias)
+			}
+			if !reflect.DeepEqual(aliases, test.wantAliases) {
+				t.Errorf("read the aliases %v, want %v", aliases, test.wantAliases)
+			}
+			if !reflect.DeepEqual(errorLines, test.wantErrorLines) {
+				t.Errorf("malformed records on lines %v, want %v", errorLines, test.wantErrorLines)
+			}
+		})
+	}
+}
+
+func TestCSVRecordReaderRequiresHeader(t *testing.T) {
+	for input, wantErr := range map[string]string{
+		"":                      "header row",
+		"alias,url\na,https://": "'originalUrl' column",
+	} {
+		reader, err := NewRecordReader(strings.NewReader(input), FormatCSV)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = reader.Read()
+		var recordErr *RecordError
+		if err == nil || errors.As(err, &recordErr) || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Read of %q returned %v, want an input error containing %q", input, err, wantErr)
+		}
+	}
+}
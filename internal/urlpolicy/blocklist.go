@@ -0,0 +1,51 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// blocklists can be large, so domains are kept in a set and looked up for the host
+// and each of its parent domains.
+type blocklistRule struct {
+	domains map[string]struct{}
+}
+
+// rejects URLs whose host is, or is a subdomain of, a domain listed in the file.
+// the file has one domain per line. empty lines and lines starting with '#' are ignored.
+func NewBlocklistFileRule(path string) (Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open URL blocklist: %w", err)
+	}
+	defer file.Close()
+
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.TrimPrefix(strings.ToLower(line), ".")] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URL blocklist: %w", err)
+	}
+
+	return &blocklistRule{domains: domains}, nil
+}
+
+func (r *blocklistRule) Check(ctx context.Context, target *Target) (*Violation, error) {
+	host := target.Host
+	for domain := host; domain != ""; {
+		if _, blocked := r.domains[domain]; blocked {
+			return &Violation{Rule: "blocklist", Message: fmt.Sprintf("Links to '%s' are blocked.", host)}, nil
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+package urlpolicy
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Target is a destination URL submitted for an alias.
+type Target struct {
+	// the URL as submitted.
+	Raw string
+	// the parsed URL.
+	URL *url.URL
+	// lowercased host name of the URL, without port or trailing dot.
+	Host string
+	// host the URL was submitted on, which is one of the service's own domains.
+	RequestHost string
+}
+
+// Violation describes why a URL isn't allowed.
+type Violation struct {
+	// name of the rule that was violated, e.g. "scheme".
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Rule + ": " + v.Message
+}
+
+// Rule is a single check of a destination URL.
+type Rule interface {
+	// returns a Violation if the target breaks the rule, and an error if the rule couldn't be checked.
+	Check(ctx context.Context, target *Target) (*Violation, error)
+}
+
+// Policy decides which destination URLs aliases may point to.
+type Policy struct {
+	rules []Rule
+}
+
+// creates a Policy that requires URLs to pass every rule, checked in order.
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{rules: rules}
+}
+
+// returns the first Violation of the URL, or nil if it is allowed.
+func (p *Policy) Check(ctx context.Context, rawURL string, requestHost string) (*Violation, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return &Violation{Rule: "syntax", Message: "The URL can't be parsed."}, nil
+	}
+	target := &Target{
+		Raw:         rawURL,
+		URL:         parsed,
+		Host:        strings.TrimSuffix(strings.ToLower(parsed.Hostname()), "."),
+		RequestHost: requestHost,
+	}
+
+	for _, rule := range p.rules {
+		violation, err := rule.Check(ctx, target)
+		if err != nil || violation != nil {
+			return violation, err
+		}
+	}
+	return nil, nil
+}
+
+// returns true if the host is the domain or one of its subdomains.
+func matchesDomain(host string, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,273 @@
+package urlpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maximum time spent resolving a host to check whether it is private.
+const resolveTimeout = 2 * time.Second
+
+type maxLengthRule struct {
+	maxLength int
+}
+
+// rejects URLs longer than maxLength bytes.
+func NewMaxLengthRule(maxLength int) Rule {
+	return &maxLengthRule{maxLength: maxLength}
+}
+
+func (r *maxLengthRule) Check(ctx context.Context, target *Target) (*Violation, error) {
+	if len(target.Raw) > r.maxLength {
+		return &Violation{Rule: "max_length", Message: fmt.Sprintf("The URL is longer than %d characters.", r.maxLength)}, nil
+	}
+	return nil, nil
+}
+
+type schemeRule struct {
+	schemes []string
+}
+
+// rejects URLs whose scheme isn't one of schemes, and URLs without a host.
+func NewSchemeRule(schemes []string) Rule {
+	return &schemeRule{schemes: schemes}
+}
+
+func (r *schemeRule) Check(ctx context.Context, target *Target) (*Violation, error) {
+	scheme := strings.ToLower(target.URL.Scheme)
+	for _, allowed := range r.schemes {
+		if scheme == strings.ToLower(allowed) {
+			if target.Host == "" {
+				return &Violation{Rule: "scheme", Message: "The URL has no host."}, nil
+			}
+			return nil, nil
+		}
+	}
+	return &Violation{Rule: "scheme", Message: fmt.Sprintf("The scheme '%s' isn't allowed, use one of: %s.", scheme, strings.Join(r.schemes, ", "))}, nil
+}
+
+type hostListRule struct {
+	allowed []string
+	denied  []string
+}
+
+// rejects URLs whose host is, or is a subdomain of, a denied domain. if allowed
+// isn't empty, the host must also be, or be a subdomain of, an allowed domain.
+func NewHostListRule(allowed []string, denied []string) Rule {
+	return &hostListRule{allowed: allowed, denied: denied}
+}
+
+func (r *hostListRule) Check(ctx context.Context, target *Target) (*Violation, error) {
+	host := target.Host
+
+	for _, domain := range r.denied {
+		if matchesDomain(host, domain) {
+			return &Violation{Rule: "denied_host", Message: fmt.Sprintf("Links to '%s' aren't allowed.", host)}, nil
+		}
+	}
+
+	if len(r.allowed) == 0 {
+		return nil, nil
+	}
+	for _, domain := range r.allowed {
+		if matchesDomain(host, domain) {
+			return nil, nil
+		}
+	}
+	return &Violation{Rule: "allowed_host", Message: fmt.Sprintf("Links to '%s' aren't allowed, only to allow-listed hosts.", host)}, nil
+}
+
+type privateNetworkRule struct {
+	resolver *net.Resolver
+}
+
+// rejects URLs pointing to loopback, private, link-local or otherwise internal addresses,
+// either literally or through a host name resolving to one. hosts that can't be resolved
+// are let through, as they can't be reached by anyone.
+func NewPrivateNetworkRule(resolver *net.Resolver) Rule {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &privateNetworkRule{resolver: resolver}
+}
+
+// suffixes of host names that only resolve within a private network.
+var internalHostSuffixes = []string{"localhost", "local", "internal", "lan", "home.arpa"}
+
+func (r *privateNetworkRule) Check(ctx context.Context, target *Target) (*Violation, error) {
+	host := target.Host
+	violation := &Violation{Rule: "private_network", Message: "Links to private or internal addresses aren't allowed."}
+
+	for _, suffix := range internalHostSuffixes {
+		if matchesDomain(host, suffix) {
+			return violation, nil
+		}
+	}
+
+	if ip := parseHostIP(host); ip != nil {
+		if isInternalIP(ip) {
+			return violation, nil
+		}
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, addr := range addrs {
+		if isInternalIP(addr.IP) {
+			return violation, nil
+		}
+	}
+	return nil, nil
+}
+
+// parses a host that is an IP address, including the IPv4 forms of inet_aton accepted by
+// browsers and resolvers, e.g. 2130706433, 127.1, 0x7f.1 or 0177.0.0.1, which the pure Go
+// resolver doesn't know. returns nil for host names.
+func parseHostIP(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		number, ok := parseIPv4Number(part)
+		if !ok {
+			return nil
+		}
+		numbers[i] = number
+	}
+
+	// every part but the last is a byte, and the last one fills the remaining bytes.
+	var address uint64
+	for _, number := range numbers[:len(numbers)-1] {
+		if number > 0xff {
+			return nil
+		}
+		address = address<<8 | number
+	}
+	last := numbers[len(numbers)-1]
+	remainingBits := uint(8 * (5 - len(numbers)))
+	if last >= 1<<remainingBits {
+		return nil
+	}
+	address = address<<remainingBits | last
+
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address))
+}
+
+// parses a part of an inet_aton address, in decimal, octal with a leading 0, or hex with a leading 0x.
+func parseIPv4Number(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"):
+		base, part = 16, part[2:]
+		if part == "" {
+			return 0, true
+		}
+	case len(part) > 1 && part[0] == '0':
+		base, part = 8, part[1:]
+	}
+
+	number, err := strconv.ParseUint(part, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// special-purpose IPv4 ranges that aren't covered by the checks of net.IP: "this network",
+// carrier-grade NAT, IETF protocol assignments, benchmarking, and reserved addresses
+// including the broadcast address.
+var internalIPv4Networks = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4")
+
+// the NAT64 prefix, embedding an IPv4 address in its last 4 bytes.
+var nat64Network = mustParseCIDRs("64:ff9b::/96")[0]
+
+func isInternalIP(ip net.IP) bool {
+	// IPv4-mapped IPv6 addresses (::ffff:10.0.0.1) are checked as the IPv4 address they map to.
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if nat64Network.Contains(ip) {
+		return isInternalIP(ip[12:])
+	}
+
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+
+	for _, network := range internalIPv4Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+type selfReferenceRule struct {
+	isShortDomain func(ctx context.Context, host string) (bool, error)
+}
+
+// rejects URLs pointing back to one of the service's own short domains, which would
+// create redirect loops. isShortDomain reports whether a host is served by the service,
+// in addition to the host the URL was submitted on.
+func NewSelfReferenceRule(isShortDomain func(ctx context.Context, host string) (bool, error)) Rule {
+	return &selfReferenceRule{isShortDomain: isShortDomain}
+}
+
+func (r *selfReferenceRule) Check(ctx context.Context, target *Target) (*Violation, error) {
+	host := target.Host
+	violation := &Violation{Rule: "self_reference", Message: "Links to the short domains of this service aren't allowed."}
+
+	requestHost := strings.ToLower(target.RequestHost)
+	if hostname, _, err := net.SplitHostPort(requestHost); err == nil {
+		requestHost = hostname
+	}
+	if host != "" && host == requestHost {
+		return violation, nil
+	}
+
+	isShortDomain, err := r.isShortDomain(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for self references: %w", err)
+	}
+	if isShortDomain {
+		return violation, nil
+	}
+	return nil, nil
+}
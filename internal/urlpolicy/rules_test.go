@@ -0,0 +1,177 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"192.0.0.170", true},
+		{"198.18.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"::", true},
+		{"fc00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:100.64.0.1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"8.8.8.8", false},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		{"192.0.2.1", false},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::808:808", false},
+		{"2001:4860:4860::8888", false},
+		{"2130706433", true},
+		{"127.1", true},
+		{"0x7f.1", true},
+		{"0177.0.0.1", true},
+		{"0x7F000001", true},
+		{"10.0x10203", true},
+		{"134744072", false},
+		{"0x8.8.0x8.010", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			ip := parseHostIP(test.ip)
+			if ip == nil {
+				t.Fatalf("invalid test IP %s", test.ip)
+			}
+			if got := isInternalIP(ip); got != test.want {
+				t.Errorf("isInternalIP(%s) = %t, want %t", test.ip, got, test.want)
+			}
+		})
+	}
+}
+
+// a resolver failing every lookup, so that host names are never resolved by the tests.
+var failingResolver = &net.Resolver{
+	PreferGo: true,
+	Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+		return nil, errors.New("no network in tests")
+	},
+}
+
+func TestPolicyCheck(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("# phishing\nbad.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	blocklistRule, err := NewBlocklistFileRule(blocklist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := NewPolicy(
+		NewMaxLengthRule(64),
+		NewSchemeRule([]string{"http", "https"}),
+		NewHostListRule(nil, []string{"denied.example"}),
+		NewSelfReferenceRule(func(ctx context.Context, host string) (bool, error) {
+			return host == "acme.link", nil
+		}),
+		blocklistRule,
+		NewPrivateNetworkRule(failingResolver),
+	)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantRule string
+	}{
+		{"allowed", "https://example.com/a", ""},
+		{"unresolvable host", "https://public.example/a", ""},
+		{"unparsable", "https://exa mple.com/%zz", "syntax"},
+		{"too long", "https://example.com/" + strings.Repeat("a", 64), "max_length"},
+		{"scheme", "ftp://example.com/a", "scheme"},
+		{"no host", "https:///a", "scheme"},
+		{"denied host", "https://denied.example/a", "denied_host"},
+		{"denied subdomain", "https://www.Denied.example./a", "denied_host"},
+		{"request host", "https://sho.rt/abc", "self_reference"},
+		{"tenant domain", "https://acme.link/abc", "self_reference"},
+		{"blocklisted", "https://bad.example/a", "blocklist"},
+		{"localhost", "http://localhost:8080/a", "private_network"},
+		{"internal suffix", "http://db.internal/a", "private_network"},
+		{"private IP", "http://10.0.0.1/a", "private_network"},
+		{"carrier-grade NAT", "http://100.64.0.1/a", "private_network"},
+		{"this network", "http://0.0.0.0/a", "private_network"},
+		{"IPv4-mapped loopback", "http://[::ffff:127.0.0.1]/a", "private_network"},
+		{"IPv6 loopback", "http://[::1]/a", "private_network"},
+		{"decimal loopback", "http://2130706433/a", "private_network"},
+		{"short loopback", "http://127.1/a", "private_network"},
+		{"hex loopback", "http://0x7f.1/a", "private_network"},
+		{"octal loopback", "http://0177.0.0.1/a", "private_network"},
+		{"public IP", "http://8.8.8.8/a", ""},
+		{"decimal public IP", "http://134744072/a", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violation, err := policy.Check(context.Background(), test.url, "sho.rt:443")
+			if err != nil {
+				t.Fatalf("Check(%q) failed: %v", test.url, err)
+			}
+
+			var got string
+			if violation != nil {
+				got = violation.Rule
+			}
+			if got != test.wantRule {
+				t.Errorf("Check(%q) violated %q, want %q", test.url, got, test.wantRule)
+			}
+		})
+	}
+}
+
+func TestHostListRuleAllowList(t *testing.T) {
+	rule := NewHostListRule([]string{"example.com"}, nil)
+	policy := NewPolicy(rule)
+
+	for url, wantRule := range map[string]string{
+		"https://example.com/a":     "",
+		"https://www.example.com/a": "",
+		"https://notexample.com/a":  "allowed_host",
+		"https://example.org/a":     "allowed_host",
+	} {
+		violation, err := policy.Check(context.Background(), url, "")
+		if err != nil {
+			t.Fatalf("Check(%q) failed: %v", url, err)
+		}
+
+		var got string
+		if violation != nil {
+			got = violation.Rule
+		}
+		if got != wantRule {
+			t.Errorf("Check(%q) violated %q, want %q", url, got, wantRule)
+		}
+	}
+}
+
+func TestParseHostIP(t *testing.T) {
+	for _, host := range []string{"example.com", "1.2.3.4.5", "256.1", "1.2.3.256", "4294967296", "08.1.1.1", "0xg.1", "1..2", ""} {
+		if ip := parseHostIP(host); ip != nil {
+			t.Errorf("parseHostIP(%q) = %s, want nil", host, ip)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// returns the IP address of the peer that sent the request, ignoring any proxy headers.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// TrustedProxies are the networks of reverse proxies whose X-Forwarded-For header is trusted.
+type TrustedProxies []*net.IPNet
+
+// returns true if the ip belongs to one of the trusted networks.
+func (tp TrustedProxies) Contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range tp {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the IP address of the client that sent the request. if the request came
+// through trusted proxies, X-Forwarded-For is walked from the right and the first
+// address that isn't a trusted proxy is returned, as entries further left can be
+// spoofed by the client.
+func (tp TrustedProxies) ClientIP(r *http.Request) string {
+	ip := ClientIP(r)
+	if !tp.Contains(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+		if !tp.Contains(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// returns the scheme the client used, "http" or "https". behind a trusted proxy terminating
+// TLS it is read from the X-Forwarded-Proto header, taking the entry of the last proxy.
+func (tp TrustedProxies) Scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if !tp.Contains(ClientIP(r)) {
+		return "http"
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-Proto"), ","), ",")
+	if proto := strings.ToLower(strings.TrimSpace(forwarded[len(forwarded)-1])); proto == "https" {
+		return proto
+	}
+	return "http"
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func testTrustedProxies(t *testing.T) TrustedProxies {
+	t.Helper()
+
+	var proxies TrustedProxies
+	for _, cidr := range []string{"10.0.0.0/8", "2001:db8::/32"} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	proxies := testTrustedProxies(t)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted peer's header is ignored", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without header", "10.0.0.1:5000", nil, "10.0.0.1"},
+		{"spoofed entries left of the client", "10.0.0.1:5000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.1:5000", []string{"198.51.100.1, 10.0.0.2, 10.0.0.3"}, "198.51.100.1"},
+		{"repeated headers", "10.0.0.1:5000", []string{"1.1.1.1, 198.51.100.1", "10.0.0.2"}, "198.51.100.1"},
+		{"invalid hop", "10.0.0.1:5000", []string{"198.51.100.1, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"only trusted hops", "10.0.0.1:5000", []string{"10.0.0.2"}, "10.0.0.2"},
+		{"IPv6", "[2001:db8::1]:5000", []string{"2001:db8::2, 2001:db9::1"}, "2001:db9::1"},
+		{"remote address without port", "10.0.0.1", []string{"198.51.100.1"}, "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/abc", nil)
+			r.RemoteAddr = test.remoteAddr
+			for _, value := range test.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := proxies.ClientIP(r); got != test.want {
+				t.Errorf("ClientIP = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTrustedProxiesScheme(t *testing.T) {
+	proxies := testTrustedProxies(t)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		forwarded  []string
+		want       string
+	}{
+		{"plain request", "203.0.113.7:5000", false, nil, "http"},
+		{"TLS request", "203.0.113.7:5000", true, nil, "https"},
+		{"untrusted peer's header is ignored", "203.0.113.7:5000", false, []string{"https"}, "http"},
+		{"trusted proxy terminating TLS", "10.0.0.1:5000", false, []string{"HTTPS"}, "https"},
+		{"trusted proxy without header", "10.0.0.1:5000", false, nil, "http"},
+		{"entry of the last proxy", "10.0.0.1:5000", false, []string{"https, http"}, "http"},
+		{"repeated headers", "10.0.0.1:5000", false, []string{"http", "https"}, "https"},
+		{"unknown scheme", "10.0.0.1:5000", false, []string{"gopher"}, "http"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/abc", nil)
+			r.RemoteAddr = test.remoteAddr
+			if test.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for _, value := range test.forwarded {
+				r.Header.Add("X-Forwarded-Proto", value)
+			}
+
+			if got := proxies.Scheme(r); got != test.want {
+				t.Errorf("Scheme = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
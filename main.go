@@ -2,39 +2,56 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 
 	_ "github.com/shashwatrathod/url-shortner/docs/swagger"
+	"github.com/shashwatrathod/url-shortner/internal/analytics"
+	"github.com/shashwatrathod/url-shortner/internal/auth"
 	"github.com/shashwatrathod/url-shortner/internal/cache"
 	"github.com/shashwatrathod/url-shortner/internal/config"
 	"github.com/shashwatrathod/url-shortner/internal/db"
+	"github.com/shashwatrathod/url-shortner/internal/db/dao"
+	"github.com/shashwatrathod/url-shortner/internal/db/migrations"
 	"github.com/shashwatrathod/url-shortner/internal/handlers"
+	"github.com/shashwatrathod/url-shortner/internal/jobs"
 	"github.com/shashwatrathod/url-shortner/internal/middleware"
 	"github.com/shashwatrathod/url-shortner/internal/routes"
+	"github.com/shashwatrathod/url-shortner/internal/urlpolicy"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-// initializes and returns the db connection manager.
-func initDb(conf *config.Config) (*db.ConnectionManager, error) {
+// opens connections to the configured shards without migrating them.
+func connectDb(conf *config.Config) (*db.ConnectionManager, error) {
 	var connConfigs = make([]db.ConnectionConfig, len(conf.DBConfigs))
 
 	for i, dbConfig := range conf.DBConfigs {
 		connConfigs[i] = db.ConnectionConfig{
-			DSN: fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
-				dbConfig.DBUser,
-				dbConfig.Password,
-				dbConfig.Host,
-				dbConfig.Port,
-				dbConfig.DBName,
-			),
-			ShardName: dbConfig.DBName,
+			DSN:         dbConfig.DSN,
+			ShardID:     dbConfig.ShardID,
+			ShardName:   dbConfig.DBName,
+			ReplicaDSNs: dbConfig.ReplicaDSNs,
+			Pool: db.PoolConfig{
+				MaxOpenConns:    dbConfig.MaxOpenConns,
+				MaxIdleConns:    dbConfig.MaxIdleConns,
+				ConnMaxLifetime: dbConfig.ConnMaxLifetime,
+				ConnMaxIdleTime: dbConfig.ConnMaxIdleTime,
+			},
 		}
 	}
 
@@ -42,33 +59,165 @@ func initDb(conf *config.Config) (*db.ConnectionManager, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize ConnectionManager: %v", err)
 	}
+	return dbManager, nil
+}
+
+// returns the migrator of the shards, running the embedded migrations unless DB_MIGRATION_DIR is set.
+func initMigrator(conf *config.Config, dbManager *db.ConnectionManager) *db.Migrator {
+	var fsys fs.FS = migrations.FS
+	if conf.MigrationConfig.Dir != "" {
+		fsys = os.DirFS(conf.MigrationConfig.Dir)
+	}
+	return db.NewMigrator(dbManager, fsys, conf.MigrationConfig.LockTimeout)
+}
+
+// initializes and returns the db connection manager. migrations are applied if DB_AUTO_MIGRATE
+// is set, and the shards are checked to be on the same schema version. instances starting
+// together take turns migrating each shard, so they all check the version once migrated.
+// the shards are then checked to be the shards they are configured as.
+func initDb(conf *config.Config) (*db.ConnectionManager, error) {
+	dbManager, err := connectDb(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	migrator := initMigrator(conf, dbManager)
 
 	// Apply migrations
-	if err := dbManager.ApplyMigrations(); err != nil {
-		return nil, fmt.Errorf("failed to apply migrations: %v", err)
+	if conf.MigrationConfig.AutoMigrate {
+		if err := migrator.UpAll(ctx); err != nil {
+			dbManager.CloseAll()
+			return nil, fmt.Errorf("failed to apply migrations: %v", err)
+		}
+	}
+
+	version, err := migrator.CheckVersions(ctx)
+	if err != nil {
+		dbManager.CloseAll()
+		return nil, err
+	}
+	log.Printf("all shards are on schema version %d", version)
+
+	if err := dbManager.VerifyTopology(ctx); err != nil {
+		dbManager.CloseAll()
+		return nil, fmt.Errorf("the configured shards don't match the databases: %w", err)
 	}
 
 	return dbManager, nil
 }
 
-// initializes and returns the redis cache manager
-func initRedisCacheManager(ctx context.Context, conf *config.Config) (cache.CacheManager, error) {
-	client := redis.NewClient(&redis.Options{
+// initializes and returns the redis cache manager. if password is set, new connections
+// authenticate with its current value, so that a rotated password is picked up.
+func initRedisCacheManager(ctx context.Context, conf *config.Config, password *atomic.Pointer[string]) (cache.CacheManager, error) {
+	options := &redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", conf.RedisConfig.Host, conf.RedisConfig.Port),
 		Password: conf.RedisConfig.Password,
 		DB:       0,
-	})
+	}
+	if password != nil {
+		options.CredentialsProvider = func() (string, string) {
+			return "", *password.Load()
+		}
+	}
+
+	client := redis.NewClient(options)
 
 	return cache.NewRedisCacheManager(ctx, client)
 }
 
+// initializes the verifier for bearer tokens issued by the configured identity provider.
+func initJWTVerifier(conf *config.Config) auth.JWTVerifier {
+	keySource := auth.NewJWKSKeySource(conf.AuthConfig.JWKSURL, conf.AuthConfig.JWKSCacheTTL, nil)
+
+	return auth.NewJWKSJWTVerifier(keySource, auth.JWTVerifierOptions{
+		Issuer:      conf.AuthConfig.JWTIssuer,
+		Audience:    conf.AuthConfig.JWTAudience,
+		TenantClaim: conf.AuthConfig.JWTTenantClaim,
+	})
+}
+
+// initializes the click recorder that persists redirect analytics in the background.
+// client IPs are hashed with a salt, as unsalted hashes could be reversed by hashing every IPv4 address.
+func initClickRecorder(conf *config.Config, dbManager *db.ConnectionManager) (analytics.ClickRecorder, error) {
+	if conf.AnalyticsConfig.IPHashSalt == "" {
+		return nil, errors.New("ANALYTICS_IP_HASH_SALT must be set, as client IPs are hashed with it before they are stored")
+	}
+
+	geoIPLookup := analytics.NewNoopGeoIPLookup()
+	if conf.AnalyticsConfig.GeoIPFile != "" {
+		lookup, err := analytics.NewCIDRGeoIPLookup(conf.AnalyticsConfig.GeoIPFile)
+		if err != nil {
+			return nil, err
+		}
+		geoIPLookup = lookup
+	}
+
+	return analytics.NewBufferedClickRecorder(
+		dao.NewAliasClickDao(dbManager),
+		geoIPLookup,
+		analytics.ClickRecorderOptions{
+			BufferSize:    conf.AnalyticsConfig.BufferSize,
+			BatchSize:     conf.AnalyticsConfig.BatchSize,
+			FlushInterval: conf.AnalyticsConfig.FlushInterval,
+			IPHashSalt:    conf.AnalyticsConfig.IPHashSalt,
+		},
+	), nil
+}
+
+// builds the policy destination URLs of new aliases are checked against. links to the
+// domains of tenants are refused, as of the last refresh of tenantDomains.
+func initUrlPolicy(conf *config.Config, tenantDomains *middleware.TenantDomains) (*urlpolicy.Policy, error) {
+	policyConf := conf.UrlPolicyConfig
+
+	var publicHost string
+	if conf.AliasConfig.PublicBaseURL != "" {
+		if parsed, err := url.Parse(conf.AliasConfig.PublicBaseURL); err == nil {
+			publicHost = strings.ToLower(parsed.Hostname())
+		}
+	}
+
+	rules := []urlpolicy.Rule{
+		urlpolicy.NewMaxLengthRule(policyConf.MaxURLLength),
+		urlpolicy.NewSchemeRule(policyConf.AllowedSchemes),
+		urlpolicy.NewHostListRule(policyConf.AllowedHosts, policyConf.DeniedHosts),
+		urlpolicy.NewSelfReferenceRule(func(ctx context.Context, host string) (bool, error) {
+			return host == publicHost || tenantDomains.Contains(host), nil
+		}),
+	}
+
+	if policyConf.BlocklistFile != "" {
+		blocklist, err := urlpolicy.NewBlocklistFileRule(policyConf.BlocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, blocklist)
+	}
+
+	// resolves host names, so it runs last.
+	if policyConf.BlockPrivateNetworks {
+		rules = append(rules, urlpolicy.NewPrivateNetworkRule(nil))
+	}
+
+	return urlpolicy.NewPolicy(rules...), nil
+}
+
 // @title URL Shortener API
 // @version 1.0
 // @description API Documentation for the Go-Short URL shortening service.
 
 // @host localhost:8080
-// @BasePath /api
+// @BasePath /
 // @schemes http
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT issued by the configured identity provider, as "Bearer <token>".
 func main() {
 	// Load .env file
 	err := godotenv.Load()
@@ -76,6 +225,20 @@ func main() {
 		log.Println("Warning: Error loading .env file, relying on environment variables.")
 	}
 
+	// Commands that don't need a valid config run before it is loaded.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			if err := runConfigCommand(os.Args[2:]); err != nil {
+				log.Fatalf("config: %s", err)
+			}
+			return
+		case "help", "-h", "--help":
+			fmt.Println(usage)
+			return
+		}
+	}
+
 	conf, err := config.Load()
 	if err != nil {
 		log.Panicf("error loading config: %s", err)
@@ -83,6 +246,14 @@ func main() {
 
 	ctx := context.Background()
 
+	// Run a CLI subcommand instead of the server if one was given.
+	if len(os.Args) > 1 {
+		if err := runCommand(conf, os.Args[1:]); err != nil {
+			log.Fatalf("%s: %s", os.Args[1], err)
+		}
+		return
+	}
+
 	// Initialize the DB Connection Manager.
 	dbManager, err := initDb(conf)
 
@@ -92,24 +263,78 @@ func main() {
 
 	log.Printf("Initializing DBManager : Success")
 
+	// Check the read replicas. the CLI doesn't start the checks, so it always works with the primaries.
+	dbManager.StartReplicaHealthChecks(conf.ReplicaConfig.HealthCheckInterval, conf.ReplicaConfig.MaxLag)
+
 	// Initialize Redis Cache Manager
-	cacheManager, err := initRedisCacheManager(ctx, conf)
+	redisPassword := &atomic.Pointer[string]{}
+	redisPassword.Store(&conf.RedisConfig.Password)
+	cacheManager, err := initRedisCacheManager(ctx, conf, redisPassword)
 	if err != nil {
 		log.Fatalf("Initializing CacheManager : %s", err)
 	}
 
 	log.Printf("Initializing CacheManager : Success")
 
+	// Initialize Click Recorder
+	clickRecorder, err := initClickRecorder(conf, dbManager)
+	if err != nil {
+		log.Fatalf("Initializing ClickRecorder : %s", err)
+	}
+
+	log.Printf("Initializing ClickRecorder : Success")
+
+	// Start the job maintaining the click rollups used by the stats API
+	rollupJob := analytics.NewRollupJob(dao.NewAliasStatsDao(dbManager), conf.AnalyticsConfig.RollupInterval)
+	rollupJob.Start()
+
+	// Initialize the real-time click counters and the job persisting them
+	clickCounter := analytics.NewCachedClickCounter(cacheManager, dao.NewAliasClickCounterDao(dbManager), conf.AnalyticsConfig.IPHashSalt)
+	counterFlushJob := analytics.NewCounterFlushJob(clickCounter, conf.AnalyticsConfig.CounterFlushInterval)
+	counterFlushJob.Start()
+
+	// Re-read secret files to pick up rotated credentials
+	var secretRefreshJob *jobs.PeriodicJob
+	if len(conf.SecretsConfig.Files) > 0 {
+		secretRefreshJob = newSecretRefreshJob(conf, dbManager, redisPassword)
+		secretRefreshJob.Start()
+	}
+
 	// Initialize AppEnv
-	appEnv := middleware.NewAppEnv(dbManager, cacheManager)
+	appEnv := middleware.NewAppEnv(conf, dbManager, cacheManager, clickRecorder, clickCounter)
+
+	// Enable bearer token authentication if an identity provider is configured
+	if conf.AuthConfig.JWKSURL != "" {
+		appEnv.JWTVerifier = initJWTVerifier(conf)
+		log.Printf("Bearer token authentication enabled using JWKS at %s", conf.AuthConfig.JWKSURL)
+	}
+
+	// Load the tenant domains new aliases can't link to, and keep them up to date
+	if err := appEnv.TenantDomains.Refresh(ctx); err != nil {
+		log.Fatalf("Loading tenant domains : %s", err)
+	}
+	tenantDomainsJob := jobs.NewPeriodicJob("tenant domain refresh", conf.TenantConfig.CacheTTL, time.Minute, appEnv.TenantDomains.Refresh)
+	tenantDomainsJob.Start()
+
+	// Initialize the policy for destination URLs
+	appEnv.UrlPolicy, err = initUrlPolicy(conf, appEnv.TenantDomains)
+	if err != nil {
+		log.Fatalf("Initializing UrlPolicy : %s", err)
+	}
 
 	// Initialize router
 	router := mux.NewRouter()
 
-	router.Use(middleware.LoggingMiddleware)
+	// Serve requests with the AppEnv of the current configuration, replaced on reloads
+	appEnvs := middleware.NewAppEnvHolder(appEnv)
+	configReloader := newConfigReloader(appEnvs, tenantDomainsJob)
+	configReloader.Start()
+
+	router.Use(middleware.LoggingMiddleware(appEnvs))
 	router.Use(middleware.ErrorHandlingMiddleware)
 
-	router.Use(middleware.ContextMiddleware(appEnv))
+	router.Use(middleware.ContextMiddleware(appEnvs))
+	router.Use(middleware.TenantMiddleware)
 
 	// Register API routes
 	routes.RegisterRoutes(router)
@@ -121,8 +346,42 @@ func main() {
 	router.NotFoundHandler = http.HandlerFunc(handlers.NotFoundHandler)
 	router.MethodNotAllowedHandler = http.HandlerFunc(handlers.MethodNotAllowedHandler)
 
+	server := &http.Server{Addr: ":8080", Handler: router}
+
 	// Start the server
-	log.Println("Starting server on :8080")
-	log.Println("Access Swagger at http://localhost:8080/swagger/index.html")
-	http.ListenAndServe(":8080", router)
+	go func() {
+		log.Println("Starting server on :8080")
+		log.Println("Access Swagger at http://localhost:8080/swagger/index.html")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Starting server : %s", err)
+		}
+	}()
+
+	// Wait for a termination signal, then drain in-flight requests and buffered clicks.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down server..")
+	shutdownCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %s", err)
+	}
+
+	configReloader.Stop()
+	rollupJob.Stop()
+	clickCounter.Close()
+	counterFlushJob.Stop()
+	tenantDomainsJob.Stop()
+	if secretRefreshJob != nil {
+		secretRefreshJob.Stop()
+	}
+
+	if err := clickRecorder.Close(); err != nil {
+		log.Printf("Error closing ClickRecorder: %s", err)
+	}
+
+	dbManager.CloseAll()
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/jobs"
+	"github.com/shashwatrathod/url-shortner/internal/middleware"
+)
+
+// configReloader reloads the configuration on SIGHUP, and when the config file changes.
+// settings that can change while running are swapped into a new AppEnv, changes to the
+// others are reported as needing a restart.
+type configReloader struct {
+	appEnvs *middleware.AppEnvHolder
+	// refreshes the tenant domains every TENANT_CACHE_TTL, which can change while running.
+	tenantDomainsJob *jobs.PeriodicJob
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newConfigReloader(appEnvs *middleware.AppEnvHolder, tenantDomainsJob *jobs.PeriodicJob) *configReloader {
+	return &configReloader{appEnvs: appEnvs, tenantDomainsJob: tenantDomainsJob, stop: make(chan struct{})}
+}
+
+// watches for SIGHUP and changes of the config file until Stop is called.
+func (cr *configReloader) Start() {
+	reloadConf := cr.appEnvs.Load().Config.ReloadConfig
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	cr.wg.Add(1)
+	go func() {
+		defer cr.wg.Done()
+		defer signal.Stop(hangup)
+
+		// without a config file, or with polling disabled, the file isn't polled.
+		var poll <-chan time.Time
+		if reloadConf.File != "" && reloadConf.WatchInterval > 0 {
+			ticker := time.NewTicker(reloadConf.WatchInterval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+
+		lastModified := fileVersion(reloadConf.File)
+		for {
+			select {
+			case <-hangup:
+				log.Println("Received SIGHUP, reloading the configuration..")
+			case <-poll:
+				modified := fileVersion(reloadConf.File)
+				if modified == lastModified {
+					continue
+				}
+				lastModified = modified
+				log.Printf("Config file %s changed, reloading the configuration..", reloadConf.File)
+			case <-cr.stop:
+				return
+			}
+
+			if err := cr.reload(); err != nil {
+				log.Printf("Error reloading the configuration, keeping the current one: %s", err)
+			}
+		}
+	}()
+}
+
+// stops watching for changes.
+func (cr *configReloader) Stop() {
+	close(cr.stop)
+	cr.wg.Wait()
+}
+
+func (cr *configReloader) reload() error {
+	reloaded, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	current := cr.appEnvs.Load()
+	conf, restartRequired := config.Reload(current.Config, reloaded)
+
+	urlPolicy, err := initUrlPolicy(conf, current.TenantDomains)
+	if err != nil {
+		return err
+	}
+
+	cr.appEnvs.Store(current.WithConfig(conf, urlPolicy))
+	cr.tenantDomainsJob.SetInterval(conf.TenantConfig.CacheTTL)
+	log.Println("Reloaded the configuration")
+
+	for _, setting := range restartRequired {
+		log.Printf("Warning: %s changed, which needs a restart to be applied", setting)
+	}
+	return nil
+}
+
+// returns the modification time and size of the file, which change when it is rewritten.
+// config maps mounted by Kubernetes are replaced through a symlink, which os.Stat follows.
+func fileVersion(path string) string {
+	if path == "" {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", info.ModTime(), info.Size())
+}
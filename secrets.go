@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/shashwatrathod/url-shortner/internal/config"
+	"github.com/shashwatrathod/url-shortner/internal/db"
+	"github.com/shashwatrathod/url-shortner/internal/jobs"
+)
+
+// creates the job reloading the configuration to pick up rotated secrets. shards whose DSN
+// changed connect with the new one from then on, as does Redis with a changed password.
+// other changes of the configuration need a restart.
+func newSecretRefreshJob(conf *config.Config, dbManager *db.ConnectionManager, redisPassword *atomic.Pointer[string]) *jobs.PeriodicJob {
+	return jobs.NewPeriodicJob("secret refresh", conf.SecretsConfig.RefreshInterval, time.Minute, func(ctx context.Context) error {
+		reloaded, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to reload the configuration: %w", err)
+		}
+
+		var errs []error
+		for _, dbConfig := range reloaded.DBConfigs {
+			changed, err := dbManager.UpdateDSNs(dbConfig.ShardID, dbConfig.DSN, dbConfig.ReplicaDSNs)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if changed {
+				log.Printf("shard %s: connecting with rotated credentials", dbManager.ShardName(dbConfig.ShardID))
+			}
+		}
+
+		if *redisPassword.Load() != reloaded.RedisConfig.Password {
+			password := reloaded.RedisConfig.Password
+			redisPassword.Store(&password)
+			log.Printf("redis: connecting with a rotated password")
+		}
+
+		return errors.Join(errs...)
+	})
+}